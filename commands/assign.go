@@ -0,0 +1,277 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// AssignReviewsCommand requests reviews of the team's unreviewed PRs, spread
+// over the team by how many reviews each member already has open
+type AssignReviewsCommand struct {
+	Meta
+}
+
+// assignOptions are the flags accepted by `tfteam assign-reviews`
+type assignOptions struct {
+	dryRun    bool
+	all       bool
+	teams     []string
+	reviewers int
+}
+
+func (c *AssignReviewsCommand) flags(o *assignOptions) *flag.FlagSet {
+	f := c.flagSet("assign-reviews")
+	boolVar(f, &o.dryRun, "dry-run", "n", "Print the planned assignments without requesting any reviews")
+	boolVar(f, &o.all, "all", "a", "Also assign Pull Requests from repository collaborators")
+	listVar(f, &o.teams, "team", "", "A comma seperated list of `org/slug` teams to assign, instead of the configured teams. Can be repeated")
+	f.IntVar(&o.reviewers, "reviewers", 1, "Number of `reviewers` to request for each pull request")
+	return f
+}
+
+func (c AssignReviewsCommand) Help() string {
+	helpText := `
+Usage: tfteam assign-reviews [options]
+
+	Request reviews of the open pull requests by team members that have no
+	reviews yet, like "tfteam prs -w". Work in progress pull requests, and
+	ones that already have a review request, are skipped.
+
+	Pull requests are assigned oldest first, each to the team members with
+	the fewest open review requests at the time, counting the ones just
+	assigned. The PR's author, anyone in the config's out_of_office list and
+	anyone paired with the author in reviewer_exclusions are never picked.
+
+%s
+
+Examples:
+
+  $ tfteam assign-reviews --dry-run
+  Planned assignments, no reviews were requested:
+
+  Repo            Author      Title        Link                                                                  Reviewers
+  provider-aws    catsby      Add thing    https://github.com/terraform-providers/terraform-provider-aws/pull/1  radeksimko
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flags(&assignOptions{}))))
+}
+
+func (c AssignReviewsCommand) Synopsis() string {
+	return "Request reviews of unreviewed PRs, balancing the team's review load"
+}
+
+func (c AssignReviewsCommand) Run(args []string) int {
+	var opts assignOptions
+	if err := c.process(c.flags(&opts), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
+		return 1
+	}
+	if opts.reviewers < 1 {
+		c.UI.Error("--reviewers must be at least 1")
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	popts := &prsOptions{all: opts.all}
+	for _, s := range opts.teams {
+		t, err := parseTeam(s)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("--team: %s", err))
+			c.UI.Error(c.Help())
+			return 1
+		}
+		popts.teamConfigs = append(popts.teamConfigs, t)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	q := &searchQuery{}
+	q.add("is", "open")
+	q.add("is", "pr")
+	q.anyOf("author", c.authors(ctx, client, popts))
+	prs := c.searchPRs(ctx, client, q)
+	errs := c.fetchDetails(ctx, client, prs, popts)
+
+	var waiting []*TFPr
+	checked := 0
+	for i, pr := range prs {
+		if errs[i] != nil {
+			c.fail(pr.HTMLURL, errs[i])
+			continue
+		}
+		checked++
+		if pr.StatusCode() != StatusWaiting || pr.WIP || len(pr.RequestedReviewers) > 0 {
+			continue
+		}
+		waiting = append(waiting, pr)
+	}
+	sortPRs(waiting, "none", "created", false, time.Now())
+
+	out := &assignmentsOutput{
+		DryRun:      opts.dryRun,
+		Assignments: []*assignment{},
+		Load:        map[string]int{},
+	}
+	if len(waiting) > 0 {
+		out.Load = c.openReviews(ctx, client, c.reviewerPool(popts.groups))
+	}
+	for _, pr := range waiting {
+		reviewers := c.pickReviewers(out.Load, pr.GetLogin(), opts.reviewers)
+		if len(reviewers) == 0 {
+			c.fail(pr.HTMLURL, errors.New("no reviewers available"))
+			continue
+		}
+		if !opts.dryRun {
+			_, _, err := client.PullRequests.RequestReviewers(ctx, pr.Owner, pr.Repo, pr.Number, github.ReviewersRequest{Reviewers: reviewers})
+			if err != nil {
+				c.fail(pr.HTMLURL, fmt.Errorf("error requesting reviews: %s", err))
+				continue
+			}
+		}
+		for _, r := range reviewers {
+			out.Load[r]++
+		}
+		out.Assignments = append(out.Assignments, &assignment{PR: pr, Reviewers: reviewers})
+	}
+
+	if err := c.render(out); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
+	}
+
+	return c.exitStatus(checked)
+}
+
+// reviewerPool is the sorted members of the teams PRs were listed for,
+// without bots and anyone out of office
+func (m *Meta) reviewerPool(groups []teamGroup) []string {
+	seen := make(map[string]bool)
+	var pool []string
+	for _, g := range groups {
+		for _, login := range g.Members {
+			if seen[login] || m.Config.IsBot(login) || m.Config.IsOutOfOffice(login) {
+				continue
+			}
+			seen[login] = true
+			pool = append(pool, login)
+		}
+	}
+	sort.Strings(pool)
+	return pool
+}
+
+// pickReviewers returns up to n of the logins in load with the fewest open
+// reviews, leaving out the author and anyone excluded from their PRs
+func (m *Meta) pickReviewers(load map[string]int, author string, n int) []string {
+	var candidates []string
+	for login := range load {
+		if login != author && !m.Config.IsExcludedPair(author, login) {
+			candidates = append(candidates, login)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if load[a] != load[b] {
+			return load[a] < load[b]
+		}
+		return a < b
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// assignment is a PR and the reviewers requested for it
+type assignment struct {
+	PR        *TFPr
+	Reviewers []string
+}
+
+// assignmentsOutput is the result of `tfteam assign-reviews`. Load is the
+// open reviews of each member of the rotation, counting the new ones.
+type assignmentsOutput struct {
+	DryRun      bool           `json:"dry_run"`
+	Assignments []*assignment  `json:"assignments"`
+	Load        map[string]int `json:"open_reviews"`
+}
+
+func (a *assignment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Owner     string   `json:"owner"`
+		Repo      string   `json:"repo"`
+		Number    int      `json:"number"`
+		Title     string   `json:"title"`
+		Author    string   `json:"author"`
+		URL       string   `json:"url"`
+		Reviewers []string `json:"reviewers"`
+	}{
+		Owner:     a.PR.Owner,
+		Repo:      a.PR.Repo,
+		Number:    a.PR.Number,
+		Title:     a.PR.Title,
+		Author:    a.PR.GetLogin(),
+		URL:       a.PR.HTMLURL,
+		Reviewers: a.Reviewers,
+	})
+}
+
+func (o *assignmentsOutput) Header() []string {
+	return []string{"owner", "repo", "number", "title", "author", "url", "reviewers"}
+}
+
+func (o *assignmentsOutput) Rows() [][]string {
+	var rows [][]string
+	for _, a := range o.Assignments {
+		rows = append(rows, []string{a.PR.Owner, a.PR.Repo, strconv.Itoa(a.PR.Number), a.PR.Title, a.PR.GetLogin(), a.PR.HTMLURL, strings.Join(a.Reviewers, " ")})
+	}
+	return rows
+}
+
+func (o *assignmentsOutput) Table(out io.Writer) {
+	if len(o.Assignments) == 0 {
+		fmt.Fprintln(out, "No unreviewed pull requests to assign")
+		return
+	}
+	if o.DryRun {
+		fmt.Fprintf(out, "Planned assignments, no reviews were requested:\n\n")
+	} else {
+		fmt.Fprintf(out, "Requested reviews:\n\n")
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Repo\tAuthor\tTitle\tLink\tReviewers")
+	for _, a := range o.Assignments {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", strings.TrimPrefix(a.PR.Repo, "terraform-"), a.PR.GetLogin(), a.PR.TitleTruncated(), a.PR.HTMLURL, strings.Join(a.Reviewers, ", "))
+	}
+	w.Flush()
+
+	var logins []string
+	for login := range o.Load {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	fmt.Fprintf(out, "\nOpen reviews:\n\n")
+	w.Init(out, 0, 8, 1, '\t', 0)
+	for _, login := range logins {
+		fmt.Fprintf(w, "%s\t%d\n", login, o.Load[login])
+	}
+	w.Flush()
+}
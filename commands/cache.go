@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/cli"
+)
+
+// CacheCommand is the parent of the cache subcommands and only shows help
+type CacheCommand struct {
+	Meta
+}
+
+func (c CacheCommand) Help() string {
+	helpText := `
+Usage: tfteam cache <subcommand> [options]
+
+	Work with the on-disk cache of GitHub API responses. Responses are kept in
+	$XDG_CACHE_HOME/tfteam or ~/.cache/tfteam and revalidated with GitHub on each
+	use, so they are never stale. Use --no-cache on any command to skip it.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c CacheCommand) Synopsis() string {
+	return "Manage the GitHub API response cache"
+}
+
+func (c CacheCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// CacheClearCommand removes every cached response
+type CacheClearCommand struct {
+	Meta
+}
+
+func (c CacheClearCommand) Help() string {
+	helpText := `
+Usage: tfteam cache clear [options]
+
+	Remove every cached GitHub API response.
+
+%s
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flagSet("cache clear"))))
+}
+
+func (c CacheClearCommand) Synopsis() string {
+	return "Remove every cached response"
+}
+
+func (c CacheClearCommand) Run(args []string) int {
+	if err := c.process(c.flagSet("cache clear"), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	dir := CacheDir()
+	if dir == "" {
+		c.UI.Error("Unable to find the cache directory")
+		return 1
+	}
+
+	stats, err := cacheStats(dir)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading cache: %s", err))
+		return 1
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		c.UI.Error(fmt.Sprintf("Error clearing cache: %s", err))
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("Removed %d cached responses (%s) from %s", stats.Entries, formatBytes(stats.Size), dir))
+	return 0
+}
+
+// CacheStatsCommand shows how much is cached
+type CacheStatsCommand struct {
+	Meta
+}
+
+func (c CacheStatsCommand) Help() string {
+	helpText := `
+Usage: tfteam cache stats [options]
+
+	Show where the cache is, how many responses it holds and their size.
+
+%s
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flagSet("cache stats"))))
+}
+
+func (c CacheStatsCommand) Synopsis() string {
+	return "Show cache location, entry count and size"
+}
+
+func (c CacheStatsCommand) Run(args []string) int {
+	if err := c.process(c.flagSet("cache stats"), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	dir := CacheDir()
+	if dir == "" {
+		c.UI.Error("Unable to find the cache directory")
+		return 1
+	}
+
+	stats, err := cacheStats(dir)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading cache: %s", err))
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("Directory: %s", dir))
+	c.UI.Output(fmt.Sprintf("Entries:   %d", stats.Entries))
+	c.UI.Output(fmt.Sprintf("Size:      %s", formatBytes(stats.Size)))
+	if stats.Entries > 0 {
+		c.UI.Output(fmt.Sprintf("Oldest:    %s", stats.Oldest.Format(time.RFC1123)))
+		c.UI.Output(fmt.Sprintf("Newest:    %s", stats.Newest.Format(time.RFC1123)))
+	}
+	return 0
+}
+
+type cacheStatsResult struct {
+	Entries int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// cacheStats walks the cache directory. A missing directory is an empty cache.
+func cacheStats(dir string) (*cacheStatsResult, error) {
+	var stats cacheStatsResult
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp") {
+			return nil
+		}
+		stats.Entries++
+		stats.Size += info.Size()
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+		return nil
+	})
+	return &stats, err
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
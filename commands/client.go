@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/google/go-github/github"
+)
+
+// defaultUserAgent is sent with every API request unless overridden
+const defaultUserAgent = "tfteam"
+
+// transport is the pooled http.Transport shared by every client we build, so
+// concurrent workers reuse connections instead of dialing GitHub per request
+var transport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+var errMissingToken = errors.New("Missing API Token! Set GITHUB_API_TOKEN")
+
+// ClientOptions configures the GitHub client returned by NewClient
+type ClientOptions struct {
+	// Token is the personal access token used to authenticate
+	Token string
+
+	// BaseURL and UploadURL point the client at something other than
+	// api.github.com, like a GitHub Enterprise install or a local fake server.
+	// If UploadURL is empty, BaseURL is used for both.
+	BaseURL   string
+	UploadURL string
+
+	// UserAgent overrides the default "tfteam" user agent
+	UserAgent string
+
+	// Debug logs each request to stderr
+	Debug bool
+
+	// CacheDir, if set, caches GET responses on disk and revalidates them
+	// with GitHub instead of fetching them again
+	CacheDir string
+
+	// Budget, if set, records the remaining rate limit quota
+	Budget *RateBudget
+}
+
+// ClientOptionsFromEnv reads client options from the environment:
+//
+//	GITHUB_API_TOKEN     personal access token (required)
+//	GITHUB_BASE_URL      API base URL, ex: https://github.example.com/api/v3/
+//	GITHUB_UPLOAD_URL    upload URL, defaults to GITHUB_BASE_URL
+//	TFTEAM_USER_AGENT    user agent sent with each request
+func ClientOptionsFromEnv() ClientOptions {
+	return ClientOptions{
+		Token:     os.Getenv("GITHUB_API_TOKEN"),
+		BaseURL:   os.Getenv("GITHUB_BASE_URL"),
+		UploadURL: os.Getenv("GITHUB_UPLOAD_URL"),
+		UserAgent: os.Getenv("TFTEAM_USER_AGENT"),
+	}
+}
+
+// NewClient returns an authenticated GitHub client. All clients share a single
+// pooled transport, so build one per command run and hand it to the workers.
+func NewClient(opts ClientOptions) (*github.Client, error) {
+	if opts.Token == "" {
+		return nil, errMissingToken
+	}
+
+	var rt http.RoundTripper = transport
+	if opts.Debug {
+		rt = &debugTransport{Transport: rt}
+	}
+	if opts.CacheDir != "" {
+		rt = &cacheTransport{Dir: opts.CacheDir, Transport: rt}
+	}
+	rt = &retryTransport{Transport: rt, Budget: opts.Budget}
+
+	hc := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.Token}),
+			Base:   rt,
+		},
+	}
+
+	client := github.NewClient(hc)
+	if opts.BaseURL != "" {
+		uploadURL := opts.UploadURL
+		if uploadURL == "" {
+			uploadURL = opts.BaseURL
+		}
+
+		var err error
+		client, err = github.NewEnterpriseClient(opts.BaseURL, uploadURL, hc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client.UserAgent = defaultUserAgent
+	if opts.UserAgent != "" {
+		client.UserAgent = opts.UserAgent
+	}
+
+	return client, nil
+}
+
+// debugTransport logs each request, its status and how long it took
+type debugTransport struct {
+	Transport http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		log.Printf("[DEBUG] %s %s: %s", req.Method, req.URL, err)
+		return nil, err
+	}
+	log.Printf("[DEBUG] %s %s: %s (%s)", req.Method, req.URL, resp.Status, time.Since(start))
+	return resp, nil
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+)
+
+// codeownersPaths are where GitHub looks for a CODEOWNERS file, in the order
+// it looks
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeownersRule is a line of a CODEOWNERS file: a gitignore style pattern and
+// the users, teams or emails owning the files it matches
+type codeownersRule struct {
+	Pattern string
+	Owners  []string
+	re      *regexp.Regexp
+}
+
+// codeowners are the rules of a CODEOWNERS file, in file order
+type codeowners []*codeownersRule
+
+// parseCodeowners parses a CODEOWNERS file. Owners are returned without their
+// "@", ex. catsby or hashicorp/terraform. Lines with invalid patterns are
+// skipped, as GitHub does.
+func parseCodeowners(content string) codeowners {
+	var rules codeowners
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		rule := &codeownersRule{Pattern: fields[0]}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				// trailing comment
+				break
+			}
+			rule.Owners = append(rule.Owners, strings.TrimPrefix(owner, "@"))
+		}
+		re, err := regexp.Compile(patternRegexp(rule.Pattern))
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// patternRegexp translates a gitignore style pattern to a regexp matching the
+// paths it covers. A pattern with a slash, other than a trailing one, is
+// relative to the repository root, otherwise it matches at any depth. A
+// pattern matching a directory matches everything under it, except for a
+// trailing "/*", which like on GitHub only matches the directory's own files.
+func patternRegexp(pattern string) string {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var re strings.Builder
+	if strings.Contains(pattern, "/") {
+		re.WriteString("^")
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		re.WriteString("^(.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			re.WriteString("[^/]*")
+		case pattern[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	switch {
+	case dirOnly:
+		re.WriteString("/.*$")
+	case strings.HasSuffix(pattern, "/*"):
+		re.WriteString("$")
+	default:
+		re.WriteString("(/.*)?$")
+	}
+	return re.String()
+}
+
+// owners returns the owners of path: those of the last rule matching it
+func (co codeowners) owners(path string) []string {
+	for i := len(co) - 1; i >= 0; i-- {
+		if co[i].re.MatchString(path) {
+			return co[i].Owners
+		}
+	}
+	return nil
+}
+
+// fetchCodeowners reads the repository's CODEOWNERS file from the first of
+// codeownersPaths that has one. Repositories without one have no rules.
+func fetchCodeowners(ctx context.Context, client *github.Client, owner, repo string) (codeowners, error) {
+	for _, p := range codeownersPaths {
+		file, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, p, nil)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting %s: %s", p, err)
+		}
+		if file == nil {
+			// a directory
+			continue
+		}
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s: %s", p, err)
+		}
+		return parseCodeowners(content), nil
+	}
+	return nil, nil
+}
+
+// codeownersCache fetches each repository's CODEOWNERS once, for all of its
+// PRs. It's safe for concurrent use.
+type codeownersCache struct {
+	mu    sync.Mutex
+	repos map[string]*codeownersEntry
+}
+
+type codeownersEntry struct {
+	once  sync.Once
+	rules codeowners
+	err   error
+}
+
+func (c *codeownersCache) get(ctx context.Context, client *github.Client, owner, repo string) (codeowners, error) {
+	c.mu.Lock()
+	if c.repos == nil {
+		c.repos = make(map[string]*codeownersEntry)
+	}
+	e, ok := c.repos[owner+"/"+repo]
+	if !ok {
+		e = &codeownersEntry{}
+		c.repos[owner+"/"+repo] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() {
+		e.rules, e.err = fetchCodeowners(ctx, client, owner, repo)
+	})
+	return e.rules, e.err
+}
+
+// getCodeOwners sets pr.CodeOwners to the owners of the files it changes,
+// from pr.Files
+func getCodeOwners(ctx context.Context, client *github.Client, pr *TFPr, cache *codeownersCache) error {
+	rules, err := cache.get(ctx, client, pr.Owner, pr.Repo)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	pr.CodeOwners = []string{}
+	for _, f := range pr.Files {
+		for _, owner := range rules.owners(f) {
+			if !seen[owner] {
+				seen[owner] = true
+				pr.CodeOwners = append(pr.CodeOwners, owner)
+			}
+		}
+	}
+	sort.Strings(pr.CodeOwners)
+	return nil
+}
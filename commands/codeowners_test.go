@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeownersOwners(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Rules  string
+		Path   string
+		Owners []string
+	}{
+		{"anchored dir", "/docs/ @docs", "docs/index.md", []string{"docs"}},
+		{"anchored dir, nested file", "/docs/ @docs", "docs/a/b.md", []string{"docs"}},
+		{"anchored dir, elsewhere", "/docs/ @docs", "website/docs/index.md", nil},
+
+		{"unanchored dir", "docs/ @docs", "docs/index.md", []string{"docs"}},
+		{"unanchored dir, nested", "docs/ @docs", "website/docs/index.md", []string{"docs"}},
+		{"unanchored dir, file of that name", "docs/ @docs", "aws/docs", nil},
+
+		{"dir star, direct child", "aws/* @aws", "aws/provider.go", []string{"aws"}},
+		{"dir star, grandchild", "aws/* @aws", "aws/internal/tags.go", nil},
+
+		{"leading double star, root", "**/logs @ops", "logs", []string{"ops"}},
+		{"leading double star, nested", "**/logs @ops", "build/logs/today.log", []string{"ops"}},
+
+		{"middle double star, direct", "a/**/b @x", "a/b", []string{"x"}},
+		{"middle double star, deep", "a/**/b @x", "a/c/d/b", []string{"x"}},
+		{"middle double star, other root", "a/**/b @x", "c/a/b", nil},
+
+		{"extension", "*.go @gophers", "aws/provider.go", []string{"gophers"}},
+		{"team owner", "* @hashicorp/terraform", "main.go", []string{"hashicorp/terraform"}},
+
+		{
+			"last match wins",
+			"* @catsby\n/aws/ @radeksimko @paddycarver\n",
+			"aws/provider.go",
+			[]string{"radeksimko", "paddycarver"},
+		},
+		{
+			"last match wins, earlier rule still applies elsewhere",
+			"* @catsby\n/aws/ @radeksimko\n",
+			"README.md",
+			[]string{"catsby"},
+		},
+		{
+			"comments and trailing comments",
+			"# owners\n*.md @docs # the docs team\n",
+			"README.md",
+			[]string{"docs"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			rules := parseCodeowners(tc.Rules)
+			actual := rules.owners(tc.Path)
+			if !reflect.DeepEqual(actual, tc.Owners) {
+				t.Fatalf("owners of %s with %q: expected %v, got %v", tc.Path, strings.TrimSpace(tc.Rules), tc.Owners, actual)
+			}
+		})
+	}
+}
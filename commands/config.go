@@ -0,0 +1,448 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// defaultConfigFiles are looked for in the users home directory, in order,
+// when neither --config nor $TFTEAM_CONFIG are given
+var defaultConfigFiles = []string{".tfteam.hcl", ".tfteam.json"}
+
+// Config holds the team rosters, organizations and repository groups the
+// commands work against. It is read from a JSON file, or an HCL one if its
+// name ends in .hcl, see ConfigPath for how that file is found.
+type Config struct {
+	GitHub GitHubConfig `json:"github" hcl:"github"`
+
+	// Orgs are the GitHub organizations tfteam looks at
+	Orgs []*OrgConfig `json:"orgs" hcl:"orgs"`
+
+	// Teams are the GitHub teams whose members' PRs and comments we track
+	Teams []*TeamConfig `json:"teams" hcl:"teams"`
+
+	// Bots are accounts that are never treated as people, even if they show up
+	// as team members or collaborators
+	Bots []string `json:"bots" hcl:"bots"`
+
+	// ExcludedReviewers are users whose PRs are left out of `tfteam prs`,
+	// usually because they review each others work outside of the rotation
+	ExcludedReviewers []string `json:"excluded_reviewers" hcl:"excluded_reviewers"`
+
+	// ReviewerExclusions are pairs of logins that `tfteam assign-reviews`
+	// never assigns to each other's PRs
+	ReviewerExclusions [][]string `json:"reviewer_exclusions" hcl:"reviewer_exclusions"`
+
+	// OutOfOffice are team members `tfteam assign-reviews` leaves out of the
+	// rotation
+	OutOfOffice []string `json:"out_of_office" hcl:"out_of_office"`
+
+	// RepoGroups are named lists of owner/name repositories, ex. "hashi" or
+	// "community"
+	RepoGroups map[string][]string `json:"repo_groups" hcl:"repo_groups"`
+
+	// RepoPatterns are substrings of the owner/name of repositories whose PRs
+	// `tfteam prs` lists
+	RepoPatterns []string `json:"repo_patterns" hcl:"repo_patterns"`
+
+	// IgnoredRepos are owner/name repositories whose PRs are skipped, even if
+	// they match RepoPatterns
+	IgnoredRepos []string `json:"ignored_repos" hcl:"ignored_repos"`
+
+	// NotificationRepoPatterns are substrings of repository names, without
+	// the owner, whose notifications `tfteam notifications` shows
+	NotificationRepoPatterns []string `json:"notification_repo_patterns" hcl:"notification_repo_patterns"`
+
+	// UnreleasedRepos are owner/name repositories `tfteam releases` skips,
+	// ex. example providers that never get released
+	UnreleasedRepos []string `json:"unreleased_repos" hcl:"unreleased_repos"`
+
+	// CoreRepo is the owner/name of Terraform core, listed separately from
+	// the providers by `tfteam releases`
+	CoreRepo string `json:"core_repo" hcl:"core_repo"`
+
+	// WIPLabels are labels that mark a PR as a work in progress, along with
+	// draft PRs and "[WIP]" titles. They're matched ignoring case.
+	WIPLabels []string `json:"wip_labels" hcl:"wip_labels"`
+}
+
+// GitHubConfig configures the API client. Environment variables take
+// precedence, see ClientOptionsFromEnv.
+type GitHubConfig struct {
+	BaseURL   string `json:"base_url" hcl:"base_url"`
+	UploadURL string `json:"upload_url" hcl:"upload_url"`
+	UserAgent string `json:"user_agent" hcl:"user_agent"`
+
+	// PerPage is the page size of list calls, up to 100. Defaults to 100.
+	PerPage int `json:"per_page" hcl:"per_page"`
+
+	// Concurrency is the number of concurrent API requests when --concurrency
+	// isn't given. Defaults to 5.
+	Concurrency int `json:"concurrency" hcl:"concurrency"`
+}
+
+// OrgConfig is a GitHub organization and what we use it for
+type OrgConfig struct {
+	Name string `json:"name" hcl:"name"`
+
+	// Collaborators includes the org's outside collaborators in `prs -c`
+	Collaborators bool `json:"collaborators" hcl:"collaborators"`
+
+	// Repositories includes the org's public repositories when listing releases
+	// or searching "all" repositories
+	Repositories bool `json:"repositories" hcl:"repositories"`
+}
+
+// TeamConfig is a GitHub team. Members are used where we can't ask the API,
+// ex. when checking notification comments.
+type TeamConfig struct {
+	Name    string   `json:"name" hcl:"name"`
+	Org     string   `json:"org" hcl:"org"`
+	Slug    string   `json:"slug" hcl:"slug"`
+	ID      int64    `json:"id" hcl:"id"`
+	Members []string `json:"members" hcl:"members"`
+}
+
+// String is the team's name, or org/slug if it has none
+func (t *TeamConfig) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Org + "/" + t.Slug
+}
+
+// hashiRepos are the providers HashiCorp maintains
+var hashiRepos = []string{
+	"terraform-providers/terraform-provider-aws",
+	"terraform-providers/terraform-provider-azurerm",
+	"terraform-providers/terraform-provider-consul",
+	"terraform-providers/terraform-provider-google",
+	"terraform-providers/terraform-provider-kubernetes",
+	"terraform-providers/terraform-provider-nomad",
+	"terraform-providers/terraform-provider-opc",
+	"terraform-providers/terraform-provider-vault",
+	"terraform-providers/terraform-provider-vsphere",
+}
+
+// DefaultConfig returns the configuration used when no config file is found.
+// It matches what the commands had hardcoded before the config file existed.
+func DefaultConfig() *Config {
+	return &Config{
+		Orgs: []*OrgConfig{
+			{Name: "terraform-providers", Collaborators: true, Repositories: true},
+			{Name: "hashicorp"},
+		},
+		Teams: []*TeamConfig{
+			{
+				Name: "Terraform",
+				Org:  "hashicorp",
+				Slug: "terraform",
+				ID:   1836975,
+				Members: []string{
+					"mitchellh",
+					"apparentlymart",
+					"jbardin",
+					"phinze",
+					"paddycarver",
+					"catsby",
+					"radeksimko",
+					"tombuildsstuff",
+					"grubernaut",
+					"mbfrahry",
+					"vancluever",
+				},
+			},
+		},
+		Bots:              []string{"hashicorp-fossa", "tf-release-bot"},
+		ExcludedReviewers: []string{"apparentlymart", "jbardin"},
+		ReviewerExclusions: [][]string{
+			{"apparentlymart", "jbardin"},
+		},
+		RepoGroups: map[string][]string{
+			"hashi": hashiRepos,
+		},
+		RepoPatterns:             []string{"terraform", "tfteam", "engservices-teamcity", "tf-deploy"},
+		IgnoredRepos:             []string{"hashibot-test/terraform-provider-archive"},
+		NotificationRepoPatterns: []string{"terraform", "tfteam"},
+		UnreleasedRepos:          []string{"terraform-providers/terraform-provider-scaffolding"},
+		CoreRepo:                 "hashicorp/terraform",
+		WIPLabels:                []string{"wip", "work-in-progress"},
+	}
+}
+
+// ConfigPath returns the config file to load. An explicit path (from
+// --config) wins, then $TFTEAM_CONFIG, then the first of ~/.tfteam.hcl and
+// ~/.tfteam.json that exists. An empty result means the defaults should be
+// used.
+func ConfigPath(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	if p := os.Getenv("TFTEAM_CONFIG"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range defaultConfigFiles {
+		p := filepath.Join(home, name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// LoadConfig reads and validates the config file at path. An empty path
+// returns DefaultConfig.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		return DefaultConfig(), nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %s", err)
+	}
+
+	var cfg Config
+	if filepath.Ext(path) == ".hcl" && !isJSON(raw) {
+		err = decodeHCL(raw, &cfg)
+		if merr, ok := err.(*multierror.Error); ok {
+			// unknown keys, listed one by one like Validate's errors
+			return nil, merr
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %s", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks the config for schema errors, returning all of them at once
+func (c *Config) Validate() error {
+	var result *multierror.Error
+
+	if c.GitHub.PerPage < 0 || c.GitHub.PerPage > 100 {
+		result = multierror.Append(result, fmt.Errorf("github.per_page: must be between 1 and 100, got %d", c.GitHub.PerPage))
+	}
+
+	if c.GitHub.Concurrency < 0 {
+		result = multierror.Append(result, fmt.Errorf("github.concurrency: can't be negative, got %d", c.GitHub.Concurrency))
+	}
+
+	seenOrgs := make(map[string]bool)
+	for i, o := range c.Orgs {
+		if o == nil || o.Name == "" {
+			result = multierror.Append(result, fmt.Errorf("orgs[%d]: name is required", i))
+			continue
+		}
+		if seenOrgs[o.Name] {
+			result = multierror.Append(result, fmt.Errorf("orgs[%d]: duplicate org %q", i, o.Name))
+		}
+		seenOrgs[o.Name] = true
+	}
+
+	for i, t := range c.Teams {
+		if t == nil {
+			result = multierror.Append(result, fmt.Errorf("teams[%d]: empty team", i))
+			continue
+		}
+		if t.Org == "" {
+			result = multierror.Append(result, fmt.Errorf("teams[%d]: org is required", i))
+		}
+		if t.Slug == "" && t.ID == 0 {
+			result = multierror.Append(result, fmt.Errorf("teams[%d]: one of slug or id is required", i))
+		}
+		for j, m := range t.Members {
+			if m == "" {
+				result = multierror.Append(result, fmt.Errorf("teams[%d].members[%d]: empty login", i, j))
+			}
+		}
+	}
+
+	for name, repos := range c.RepoGroups {
+		if name == "" || name == "all" {
+			result = multierror.Append(result, fmt.Errorf("repo_groups: %q is not a valid group name", name))
+		}
+		for j, r := range repos {
+			if !validRepoName(r) {
+				result = multierror.Append(result, fmt.Errorf("repo_groups.%s[%d]: %q is not in owner/name form", name, j, r))
+			}
+		}
+	}
+
+	for i, pair := range c.ReviewerExclusions {
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			result = multierror.Append(result, fmt.Errorf("reviewer_exclusions[%d]: must be a pair of logins", i))
+		}
+	}
+
+	for i, login := range c.OutOfOffice {
+		if login == "" {
+			result = multierror.Append(result, fmt.Errorf("out_of_office[%d]: empty login", i))
+		}
+	}
+
+	for i, l := range c.WIPLabels {
+		if l == "" {
+			result = multierror.Append(result, fmt.Errorf("wip_labels[%d]: empty label", i))
+		}
+	}
+
+	for i, r := range c.IgnoredRepos {
+		if !validRepoName(r) {
+			result = multierror.Append(result, fmt.Errorf("ignored_repos[%d]: %q is not in owner/name form", i, r))
+		}
+	}
+
+	for i, r := range c.UnreleasedRepos {
+		if !validRepoName(r) {
+			result = multierror.Append(result, fmt.Errorf("unreleased_repos[%d]: %q is not in owner/name form", i, r))
+		}
+	}
+
+	if c.CoreRepo != "" && !validRepoName(c.CoreRepo) {
+		result = multierror.Append(result, fmt.Errorf("core_repo: %q is not in owner/name form", c.CoreRepo))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func validRepoName(r string) bool {
+	parts := strings.Split(r, "/")
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
+}
+
+// TeamMembers returns the configured members of every team, without bots
+func (c *Config) TeamMembers() []string {
+	seen := make(map[string]bool)
+	var members []string
+	for _, t := range c.Teams {
+		for _, m := range t.Members {
+			if seen[m] || c.IsBot(m) {
+				continue
+			}
+			seen[m] = true
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// IsTeamMember reports whether login is a configured member of any team
+func (c *Config) IsTeamMember(login string) bool {
+	for _, m := range c.TeamMembers() {
+		if m == login {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBot reports whether login is a configured bot account
+func (c *Config) IsBot(login string) bool {
+	return containsString(c.Bots, login)
+}
+
+// IsExcludedReviewer reports whether login's PRs should be left out of `prs`
+func (c *Config) IsExcludedReviewer(login string) bool {
+	return containsString(c.ExcludedReviewers, login)
+}
+
+// IsExcludedPair reports whether reviewer shouldn't be assigned author's PRs,
+// see ReviewerExclusions
+func (c *Config) IsExcludedPair(author, reviewer string) bool {
+	for _, pair := range c.ReviewerExclusions {
+		if len(pair) != 2 {
+			continue
+		}
+		if (pair[0] == author && pair[1] == reviewer) || (pair[0] == reviewer && pair[1] == author) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOutOfOffice reports whether login is out of the review rotation
+func (c *Config) IsOutOfOffice(login string) bool {
+	return containsString(c.OutOfOffice, login)
+}
+
+// CollaboratorOrgs returns the orgs whose outside collaborators we include
+func (c *Config) CollaboratorOrgs() []string {
+	var orgs []string
+	for _, o := range c.Orgs {
+		if o.Collaborators {
+			orgs = append(orgs, o.Name)
+		}
+	}
+	return orgs
+}
+
+// RepositoryOrgs returns the orgs whose repositories we list
+func (c *Config) RepositoryOrgs() []string {
+	var orgs []string
+	for _, o := range c.Orgs {
+		if o.Repositories {
+			orgs = append(orgs, o.Name)
+		}
+	}
+	return orgs
+}
+
+// MatchesRepo reports whether `tfteam prs` lists the PRs of the owner/name
+// repository: it matches one of the RepoPatterns and isn't ignored.
+func (c *Config) MatchesRepo(repo string) bool {
+	if containsString(c.IgnoredRepos, repo) {
+		return false
+	}
+	return containsAny(repo, c.RepoPatterns)
+}
+
+// MatchesNotificationRepo reports whether `tfteam notifications` shows the
+// notifications of the repository with this name, without its owner
+func (c *Config) MatchesNotificationRepo(name string) bool {
+	return containsAny(name, c.NotificationRepoPatterns)
+}
+
+// IsUnreleased reports whether `tfteam releases` skips the owner/name
+// repository
+func (c *Config) IsUnreleased(repo string) bool {
+	return containsString(c.UnreleasedRepos, repo)
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
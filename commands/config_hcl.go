@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// decodeHCL decodes an HCL config into cfg. Like the JSON decoder with
+// DisallowUnknownFields, it rejects keys cfg has no field for.
+//
+// Lists of objects, ex. teams, are written as lists:
+//
+//	teams = [
+//	  { org = "hashicorp", slug = "terraform" },
+//	]
+func decodeHCL(raw []byte, cfg *Config) error {
+	file, err := hcl.ParseBytes(raw)
+	if err != nil {
+		return err
+	}
+	if errs := unknownHCLKeys(file.Node, reflect.TypeOf(cfg).Elem(), ""); len(errs) > 0 {
+		return multierror.Append(nil, errs...)
+	}
+	return hcl.DecodeObject(cfg, file.Node)
+}
+
+// isJSON reports whether raw is JSON the way the HCL parser decides it, by its
+// first non-space character. hcl decodes a JSON list of objects into one
+// element per key when the slice holds pointers, ex. teams, so the config
+// loader hands JSON to encoding/json even when the file ends in .hcl.
+func isJSON(raw []byte) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// unknownHCLKeys returns an error for each key under node that isn't the hcl
+// tag of a field of the struct type t, recursing into struct fields and lists
+// of structs. path is where node is, ex. "teams[0]".
+func unknownHCLKeys(node ast.Node, t reflect.Type, path string) []error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		// a type mismatch, which the decoder reports
+		return nil
+	}
+
+	var errs []error
+	for _, item := range list.Items {
+		errs = append(errs, unknownHCLItemKeys(item.Keys, item.Val, t, path)...)
+	}
+	return errs
+}
+
+// unknownHCLItemKeys checks one item. Items can have several keys, ex. the
+// block `github "per_page" { ... }`, each naming a field of the one before.
+func unknownHCLItemKeys(keys []*ast.ObjectKey, val ast.Node, t reflect.Type, path string) []error {
+	key, ok := keys[0].Token.Value().(string)
+	if !ok {
+		return nil
+	}
+	name := key
+	if path != "" {
+		name = path + "." + key
+	}
+
+	field, ok := hclField(t, key)
+	if !ok {
+		return []error{fmt.Errorf("%s: unknown key", name)}
+	}
+
+	ft := field.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	switch {
+	case ft.Kind() == reflect.Struct && len(keys) > 1:
+		return unknownHCLItemKeys(keys[1:], val, ft, name)
+	case ft.Kind() == reflect.Struct:
+		return unknownHCLKeys(val, ft, name)
+	case ft.Kind() == reflect.Slice:
+		et := ft.Elem()
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		if et.Kind() != reflect.Struct {
+			return nil
+		}
+		l, ok := val.(*ast.ListType)
+		if !ok {
+			return unknownHCLKeys(val, et, name)
+		}
+		var errs []error
+		for i, elem := range l.List {
+			errs = append(errs, unknownHCLKeys(elem, et, fmt.Sprintf("%s[%d]", name, i))...)
+		}
+		return errs
+	}
+	return nil
+}
+
+// hclField finds the field of the struct type t whose hcl tag is key
+func hclField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.Split(f.Tag.Get("hcl"), ",")[0] == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
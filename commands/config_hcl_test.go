@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigHCL(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Config  string
+		PerPage int
+		Teams   int
+		Err     string
+	}{
+		{
+			"hcl",
+			`github { per_page = 50 }
+teams = [{ org = "hashicorp", slug = "terraform" }]`,
+			50, 1, "",
+		},
+		{
+			"json",
+			`{"github": {"per_page": 25}, "teams": [{"org": "hashicorp", "slug": "terraform"}]}`,
+			25, 1, "",
+		},
+		{"unknown top level key", `botz = ["x"]`, 0, 0, "botz: unknown key"},
+		{"unknown nested key", `github { per_pag = 1 }`, 0, 0, "github.per_pag: unknown key"},
+		{"unknown key in a list", `teams = [{ nmae = "x" }]`, 0, 0, "teams[0].nmae: unknown key"},
+		{"unknown json key", `{"github": {"per_pag": 1}}`, 0, 0, `unknown field "per_pag"`},
+		{"syntax error", `github {`, 0, 0, "error parsing config"},
+	}
+
+	dir, err := ioutil.TempDir("", "tfteam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("%d.hcl", i))
+			if err := ioutil.WriteFile(path, []byte(tc.Config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfig(path)
+			if tc.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Err) {
+					t.Fatalf("expected error containing %q, got %v", tc.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if cfg.GitHub.PerPage != tc.PerPage {
+				t.Fatalf("expected per_page %d, got %d", tc.PerPage, cfg.GitHub.PerPage)
+			}
+			if len(cfg.Teams) != tc.Teams {
+				t.Fatalf("expected %d teams, got %d", tc.Teams, len(cfg.Teams))
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/cli"
+)
+
+// ConfigCommand is the parent of the config subcommands and only shows help
+type ConfigCommand struct {
+	Meta
+}
+
+func (c ConfigCommand) Help() string {
+	helpText := `
+Usage: tfteam config <subcommand> [options]
+
+	Work with the tfteam config file.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c ConfigCommand) Synopsis() string {
+	return "Work with the tfteam config file"
+}
+
+func (c ConfigCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// ConfigValidateCommand checks a config file for schema errors
+type ConfigValidateCommand struct {
+	Meta
+}
+
+func (c ConfigValidateCommand) Help() string {
+	helpText := `
+Usage: tfteam config validate [options] [path]
+
+	Validate a tfteam config file and report any schema errors. If no path is
+	given, the file from --config, $TFTEAM_CONFIG, ~/.tfteam.hcl or
+	~/.tfteam.json is used.
+
+%s
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flagSet("config validate"))))
+}
+
+func (c ConfigValidateCommand) Synopsis() string {
+	return "Validate the tfteam config file"
+}
+
+func (c ConfigValidateCommand) Run(args []string) int {
+	// only parse the flags; process would fail on a broken file before we
+	// can report on it
+	f := c.flagSet("config validate")
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	path := c.configPath
+	if f.NArg() > 0 {
+		path = f.Arg(0)
+	}
+	path = ConfigPath(path)
+	if path == "" {
+		c.UI.Error("No config file found; pass a path, --config, or set $TFTEAM_CONFIG")
+		return 1
+	}
+
+	if _, err := LoadConfig(path); err != nil {
+		c.UI.Error(fmt.Sprintf("%s is invalid:", path))
+		if merr, ok := err.(*multierror.Error); ok {
+			for _, e := range merr.Errors {
+				c.UI.Error(fmt.Sprintf("  - %s", e))
+			}
+		} else {
+			c.UI.Error(fmt.Sprintf("  - %s", err))
+		}
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("%s is valid", path))
+	return 0
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// Exit codes, so cron jobs can tell a partial run from one that got nothing
+const (
+	exitOK      = 0
+	exitFailed  = 1
+	exitPartial = 2
+)
+
+// itemError is the failure of one item of a command's results, ex. a repo
+// whose tags couldn't be listed
+type itemError struct {
+	Item string
+	Err  error
+}
+
+func (e *itemError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Item, e.Err)
+}
+
+func (e *itemError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Item  string `json:"item"`
+		Error string `json:"error"`
+	}{e.Item, e.Err.Error()})
+}
+
+// fail records that item failed with err. Failures are listed after the
+// results by render and decide the exit status. Errors from an interrupted
+// run aren't failures, render warns that the results are incomplete instead.
+func (m *Meta) fail(item string, err error) {
+	if m.ctx != nil && m.ctx.Err() != nil {
+		return
+	}
+	m.failures = multierror.Append(m.failures, &itemError{Item: item, Err: err})
+}
+
+// failureList returns the recorded failures
+func (m *Meta) failureList() []error {
+	if m.failures == nil {
+		return nil
+	}
+	return m.failures.Errors
+}
+
+// exitStatus is the exit code of a command that got succeeded results:
+// exitFailed if there were failures and no results at all, exitPartial if
+// there were some failures or the run was interrupted, and exitOK otherwise.
+func (m *Meta) exitStatus(succeeded int) int {
+	failed := len(m.failureList())
+	switch {
+	case failed > 0 && succeeded == 0:
+		return exitFailed
+	case failed > 0:
+		return exitPartial
+	case m.ctx != nil && m.ctx.Err() != nil:
+		return exitPartial
+	}
+	return exitOK
+}
+
+// writeFailures writes the Failures section of the table and markdown formats
+func writeFailures(w io.Writer, failures []error, markdown bool) {
+	if len(failures) == 0 {
+		return
+	}
+	if markdown {
+		fmt.Fprintf(w, "\n### Failures\n\n")
+		for _, err := range failures {
+			fmt.Fprintf(w, "- %s\n", err)
+		}
+		return
+	}
+	fmt.Fprintf(w, "\nFailures:\n")
+	for _, err := range failures {
+		fmt.Fprintf(w, "  - %s\n", err)
+	}
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// globalFlags are registered on every command by Meta.flagSet, and listed
+// separately in the generated help
+var globalFlags = map[string]bool{
+	"config":      true,
+	"format":      true,
+	"concurrency": true,
+	"debug":       true,
+	"no-cache":    true,
+	"timeout":     true,
+}
+
+// stringSliceValue is a flag.Value for comma separated lists. The flag can be
+// given more than once, ex. `-u=catsby,jbardin -u=radeksimko`.
+type stringSliceValue []string
+
+func (s *stringSliceValue) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceValue) Set(v string) error {
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*s = append(*s, part)
+		}
+	}
+	return nil
+}
+
+// boolVar registers a bool flag under a long name and a short alias
+func boolVar(f *flag.FlagSet, p *bool, name, alias string, usage string) {
+	f.BoolVar(p, name, *p, usage)
+	if alias != "" {
+		f.BoolVar(p, alias, *p, "")
+	}
+}
+
+// stringVar registers a string flag under a long name and a short alias
+func stringVar(f *flag.FlagSet, p *string, name, alias string, usage string) {
+	f.StringVar(p, name, *p, usage)
+	if alias != "" {
+		f.StringVar(p, alias, *p, "")
+	}
+}
+
+// listVar registers a comma separated list flag under a long name and a short
+// alias
+func listVar(f *flag.FlagSet, p *[]string, name, alias string, usage string) {
+	v := (*stringSliceValue)(p)
+	f.Var(v, name, usage)
+	if alias != "" {
+		f.Var(v, alias, "")
+	}
+}
+
+// flagsHelp renders the options registered on f for a command's Help. Aliases
+// registered with boolVar, stringVar or listVar are listed together, and the
+// global options are listed last.
+func flagsHelp(f *flag.FlagSet) string {
+	type option struct {
+		names   []string
+		flag    *flag.Flag
+		aliases []string
+	}
+
+	var options []*option
+	byValue := make(map[flag.Value]*option)
+	f.VisitAll(func(fl *flag.Flag) {
+		o, ok := byValue[fl.Value]
+		if !ok {
+			o = &option{}
+			byValue[fl.Value] = o
+			options = append(options, o)
+		}
+		if fl.Usage != "" {
+			o.flag = fl
+		} else {
+			o.aliases = append(o.aliases, fl.Name)
+		}
+	})
+
+	var local, global bytes.Buffer
+	for _, o := range options {
+		if o.flag == nil {
+			continue
+		}
+		w := &local
+		if globalFlags[o.flag.Name] {
+			w = &global
+		}
+
+		name, usage := flag.UnquoteUsage(o.flag)
+		opt := "--" + o.flag.Name
+		if name != "" {
+			opt = fmt.Sprintf("%s=<%s>", opt, name)
+		}
+		names := []string{opt}
+		sort.Strings(o.aliases)
+		for _, a := range o.aliases {
+			if len(a) == 1 {
+				names = append(names, "-"+a)
+			} else {
+				names = append(names, "--"+a)
+			}
+		}
+		opt = strings.Join(names, ", ")
+		if d := o.flag.DefValue; d != "" && d != "false" && d != "0" && d != "0s" {
+			usage = fmt.Sprintf("%s (default: %s)", usage, d)
+		}
+		fmt.Fprintf(w, "\t%s\t%s\n", opt, usage)
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 4, ' ', 0)
+	if local.Len() > 0 {
+		fmt.Fprintln(tw, "Options:")
+		fmt.Fprintln(tw)
+		tw.Write(local.Bytes())
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintln(tw, "Global Options:")
+	fmt.Fprintln(tw)
+	tw.Write(global.Bytes())
+	tw.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+)
+
+// cacheHeader is set on responses served from the cache
+const cacheHeader = "X-Tfteam-Cache"
+
+// cacheTransport keeps GET responses on disk and revalidates them with
+// If-None-Match and If-Modified-Since. GitHub answers with a 304 when nothing
+// changed, and those don't count against the rate limit, so repeated runs of
+// `prs` and `releases` are mostly free.
+type cacheTransport struct {
+	Dir       string
+	Transport http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.Transport.RoundTrip(req)
+	}
+
+	file := t.path(req)
+	cached := t.load(file, req)
+
+	if cached != nil {
+		// per RoundTripper contract we don't modify the callers request
+		req = cloneRequest(req)
+		if etag := cached.Header.Get("ETag"); etag != "" && req.Header.Get("If-None-Match") == "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); lm != "" && req.Header.Get("If-Modified-Since") == "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		// keep the fresh rate limit and date headers from the 304
+		for k, v := range resp.Header {
+			cached.Header[k] = v
+		}
+		cached.Header.Set(cacheHeader, "hit")
+		cached.Request = req
+		return cached, nil
+	}
+
+	if resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "") {
+		t.store(file, resp)
+	}
+
+	return resp, nil
+}
+
+// path returns the cache file for req. The Authorization header is part of
+// the key so different tokens never share responses.
+func (t *cacheTransport) path(req *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(req.URL.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Header.Get("Accept")))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Header.Get("Authorization")))
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(t.Dir, key[:2], key)
+}
+
+// load returns the cached response in file, or nil if there isn't one
+func (t *cacheTransport) load(file string, req *http.Request) *http.Response {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), req)
+	if err != nil {
+		// corrupt entry, it will be replaced on the next 200
+		return nil
+	}
+	return resp
+}
+
+// store writes resp to file. Errors are ignored, the cache is best effort.
+func (t *cacheTransport) store(file string, resp *http.Response) {
+	raw, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(raw)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	os.Rename(tmp.Name(), file)
+}
+
+// cloneRequest returns a copy of req with its own headers
+func cloneRequest(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	return r
+}
+
+// CacheDir is where responses are cached: $XDG_CACHE_HOME/tfteam, or
+// ~/.cache/tfteam
+func CacheDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "tfteam")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cache", "tfteam")
+}
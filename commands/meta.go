@@ -0,0 +1,183 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/cli"
+)
+
+// outputFormats are the accepted values of the global --format flag
+var outputFormats = []string{"table", "json", "csv", "markdown"}
+
+// Meta holds the UI, configuration and global options shared by every command
+type Meta struct {
+	UI cli.Ui
+
+	// Context is cancelled when tfteam is interrupted. Commands derive their
+	// context from it with context().
+	Context context.Context
+
+	// Config is loaded by process from the file given with --config,
+	// $TFTEAM_CONFIG, ~/.tfteam.hcl or ~/.tfteam.json
+	Config *Config
+
+	// global flags, see flagSet
+	configPath  string
+	format      string
+	concurrency int
+	debug       bool
+	noCache     bool
+	timeout     time.Duration
+
+	// ctx is the context handed out by context(), checked by render to
+	// warn about partial results
+	ctx context.Context
+
+	// failures are the per item errors recorded with fail
+	failures *multierror.Error
+
+	// budget is the rate limit quota seen by the client, shown after the
+	// output by render
+	budget *RateBudget
+}
+
+// flagSet returns a FlagSet for the named command with the global options
+// registered on it. Commands add their own flags and hand it to process.
+func (m *Meta) flagSet(name string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.StringVar(&m.configPath, "config", "", "Path to the config `file`. Defaults to $TFTEAM_CONFIG, ~/.tfteam.hcl or ~/.tfteam.json")
+	f.StringVar(&m.format, "format", "table", "Output `format`: table, json, csv or markdown")
+	f.IntVar(&m.concurrency, "concurrency", 5, "Number of concurrent API `requests`")
+	f.BoolVar(&m.debug, "debug", false, "Log each API request to stderr")
+	f.BoolVar(&m.noCache, "no-cache", false, "Don't use or update the on-disk response cache")
+	f.DurationVar(&m.timeout, "timeout", 0, "Stop after this `duration`, ex. 2m, and print the results collected so far")
+
+	// we report errors ourselves, see process
+	f.SetOutput(ioutil.Discard)
+	f.Usage = func() {}
+	return f
+}
+
+// process parses args with f, checks the global options and loads the config
+func (m *Meta) process(f *flag.FlagSet, args []string) error {
+	_, err := m.processArgs(f, args)
+	return err
+}
+
+// processArgs is process for commands that take positional arguments, one for
+// each of names, ex. "pr-url". Flags can come before or after them. It returns
+// the arguments in order.
+func (m *Meta) processArgs(f *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	var positional []string
+	for {
+		if err := f.Parse(args); err != nil {
+			return nil, err
+		}
+		if f.NArg() == 0 {
+			break
+		}
+		positional = append(positional, f.Arg(0))
+		args = f.Args()[1:]
+	}
+	if len(positional) > len(names) {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(positional[len(names):], " "))
+	}
+	if len(positional) < len(names) {
+		return nil, fmt.Errorf("missing arguments: %s", strings.Join(names[len(positional):], " "))
+	}
+
+	if !containsString(outputFormats, m.format) {
+		return nil, fmt.Errorf("unsupported output format %q", m.format)
+	}
+
+	if m.concurrency < 1 {
+		return nil, errors.New("--concurrency must be at least 1")
+	}
+
+	if m.timeout < 0 {
+		return nil, errors.New("--timeout can't be negative")
+	}
+
+	cfg, err := LoadConfig(ConfigPath(m.configPath))
+	if err != nil {
+		return nil, err
+	}
+	m.Config = cfg
+
+	// the config's concurrency applies unless the flag was given
+	if cfg.GitHub.Concurrency > 0 {
+		set := false
+		f.Visit(func(fl *flag.Flag) {
+			if fl.Name == "concurrency" {
+				set = true
+			}
+		})
+		if !set {
+			m.concurrency = cfg.GitHub.Concurrency
+		}
+	}
+
+	return positional, nil
+}
+
+// client returns a GitHub client configured from the environment, falling
+// back to the config file for anything not set there
+func (m *Meta) client() (*github.Client, error) {
+	opts := ClientOptionsFromEnv()
+	if opts.BaseURL == "" {
+		opts.BaseURL = m.Config.GitHub.BaseURL
+	}
+	if opts.UploadURL == "" {
+		opts.UploadURL = m.Config.GitHub.UploadURL
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = m.Config.GitHub.UserAgent
+	}
+	opts.Debug = m.debug
+	m.budget = &RateBudget{}
+	opts.Budget = m.budget
+	if !m.noCache {
+		opts.CacheDir = CacheDir()
+	}
+	return NewClient(opts)
+}
+
+// context returns the context for a command's run, cancelled on interrupt or
+// when --timeout runs out. Callers must call the returned cancel func.
+func (m *Meta) context() (context.Context, context.CancelFunc) {
+	ctx := m.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var cancel context.CancelFunc
+	if m.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	m.ctx = ctx
+	return ctx, cancel
+}
+
+// warnIncomplete warns that the results are partial if the run was
+// interrupted or timed out
+func (m *Meta) warnIncomplete() {
+	if m.ctx == nil {
+		return
+	}
+	switch m.ctx.Err() {
+	case context.Canceled:
+		m.UI.Warn("Interrupted, results are incomplete")
+	case context.DeadlineExceeded:
+		m.UI.Warn(fmt.Sprintf("Timed out after %s, results are incomplete", m.timeout))
+	}
+}
@@ -0,0 +1,506 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/mitchellh/cli"
+)
+
+// MetricsCommand is the parent of the metrics subcommands and only shows help
+type MetricsCommand struct {
+	Meta
+}
+
+func (c MetricsCommand) Help() string {
+	helpText := `
+Usage: tfteam metrics <subcommand> [options]
+
+	Measure how the team is doing, from the history of its pull requests.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c MetricsCommand) Synopsis() string {
+	return "Measure how the team is doing"
+}
+
+func (c MetricsCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// MetricsReviewsCommand reports how long the team's PRs waited on reviews
+type MetricsReviewsCommand struct {
+	Meta
+}
+
+// metricsReviewsOptions are the flags accepted by `tfteam metrics reviews`
+type metricsReviewsOptions struct {
+	since string
+	teams []string
+}
+
+func (c *MetricsReviewsCommand) flags(o *metricsReviewsOptions) *flag.FlagSet {
+	f := c.flagSet("metrics reviews")
+	o.since = "30d"
+	stringVar(f, &o.since, "since", "s", "Only look at pull requests closed in this `period`, ex. 30d, 2w or 36h")
+	listVar(f, &o.teams, "team", "", "A comma seperated list of `org/slug` teams to measure, instead of the configured teams. Can be repeated")
+	return f
+}
+
+func (c MetricsReviewsCommand) Help() string {
+	helpText := `
+Usage: tfteam metrics reviews [options]
+
+	Report how fast pull requests by the team and collaborators were reviewed,
+	from the ones merged or closed in the --since period. For each repository,
+	the median and 90th percentile of these are shown, timed from when the
+	pull request was opened:
+
+	  - first review: the first review by anyone but the author
+	  - first team response: the first review by a team member, or the first
+	    time one labeled, assigned, requested a review of, closed or merged it
+	  - approval: the first approving review
+	  - merge: when it was merged
+
+	For each team member, the time to review is timed from the last review
+	request made of them before their first review, or from when the pull
+	request was opened if there was none. Pull requests without a review,
+	approval or merge don't count towards that measure.
+
+%s
+
+Examples:
+
+  $ tfteam metrics reviews --since 30d
+  Review turnaround of 42 pull requests closed since Thu 09/17/2026 (median / p90)
+
+  Repo                                        PRs  First review  First team response  Approval       Merge
+  All                                         42   5h / 3d 2h    3h / 2d 4h           1d 2h / 6d 1h  1d 5h / 8d 3h
+  hashicorp/terraform                         12   9h / 4d 1h    6h / 3d 20h          2d 3h / 7d 2h  2d 6h / 9d 4h
+  terraform-providers/terraform-provider-aws  30   4h / 2d 22h   2h / 1d 19h          22h / 5d 4h    1d 1h / 7d 9h
+
+  Reviewer    Reviews  Time to review
+  radeksimko  12       4h / 2d 1h
+  catsby      9        7h / 3d 5h
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flags(&metricsReviewsOptions{}))))
+}
+
+func (c MetricsReviewsCommand) Synopsis() string {
+	return "Report review turnaround of the team's closed PRs"
+}
+
+func (c MetricsReviewsCommand) Run(args []string) int {
+	var opts metricsReviewsOptions
+	if err := c.process(c.flags(&opts), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
+		return 1
+	}
+	period, err := parsePeriod(opts.since)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("--since: %s", err))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	popts := &prsOptions{all: true, keepExcluded: true}
+	for _, s := range opts.teams {
+		t, err := parseTeam(s)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("--team: %s", err))
+			c.UI.Error(c.Help())
+			return 1
+		}
+		popts.teamConfigs = append(popts.teamConfigs, t)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	since := time.Now().Add(-period)
+	q := &searchQuery{}
+	q.add("is", "pr")
+	q.add("is", "closed")
+	q.add("closed", ">="+since.Format("2006-01-02"))
+	q.anyOf("author", c.authors(ctx, client, popts))
+	prs := c.searchPRs(ctx, client, q)
+
+	team := make(map[string]bool)
+	for _, g := range popts.groups {
+		for _, login := range g.Members {
+			team[login] = true
+		}
+	}
+
+	turnarounds := make([]*prTurnaround, len(prs))
+	perPage := c.Config.GitHub.PerPage
+	errs := runPool(ctx, c.concurrency, len(prs), func(ctx context.Context, i int) error {
+		pr := prs[i]
+		reviews, err := listReviews(ctx, client, perPage, pr.Owner, pr.Repo, pr.Number)
+		if err != nil {
+			return fmt.Errorf("error listing reviews: %s", err)
+		}
+		events, err := listIssueEvents(ctx, client, perPage, pr.Owner, pr.Repo, pr.Number)
+		if err != nil {
+			return fmt.Errorf("error listing events: %s", err)
+		}
+		turnarounds[i] = c.turnaround(pr, reviews, events, team)
+		return nil
+	})
+
+	var measured []*prTurnaround
+	for i, pr := range prs {
+		if errs[i] != nil {
+			c.fail(pr.HTMLURL, errs[i])
+			continue
+		}
+		measured = append(measured, turnarounds[i])
+	}
+
+	out := newReviewMetrics(since, measured)
+	if err := c.render(out); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
+	}
+	return c.exitStatus(len(measured))
+}
+
+// parsePeriod parses a --since period. On top of time.ParseDuration's units
+// it takes days and weeks, ex. 30d or 2w.
+func parsePeriod(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) {
+			if n <= 0 {
+				return 0, fmt.Errorf("%q must be positive", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a period, ex. 30d, 2w or 36h", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%q must be positive", s)
+	}
+	return d, nil
+}
+
+// responseEvents are the issue events that count as a response to a PR when
+// a team member other than the author makes them
+var responseEvents = []string{"labeled", "assigned", "review_requested", "closed", "merged"}
+
+// prTurnaround is how long a closed PR took to get to each step, from when
+// it was opened. Steps it never got to are nil.
+type prTurnaround struct {
+	Repo          string
+	FirstReview   *time.Duration
+	FirstResponse *time.Duration
+	Approval      *time.Duration
+	Merge         *time.Duration
+
+	// Reviews maps each team member who reviewed the PR to how long their
+	// review took from when it was requested
+	Reviews map[string]time.Duration
+}
+
+// turnaround measures pr from its reviews and issue events. team is the
+// logins of the team members.
+func (m *Meta) turnaround(pr *TFPr, reviews []*github.PullRequestReview, events []*issueEvent, team map[string]bool) *prTurnaround {
+	t := &prTurnaround{
+		Repo:    pr.Owner + "/" + pr.Repo,
+		Reviews: make(map[string]time.Duration),
+	}
+	if pr.CreatedAt == nil {
+		return t
+	}
+	opened := *pr.CreatedAt
+	author := pr.GetLogin()
+
+	// record sets step to at, unless it already happened earlier. Events
+	// made as the PR is opened, ex. its labels, can be a second early.
+	record := func(step **time.Duration, at *time.Time) {
+		if at == nil {
+			return
+		}
+		d := at.Sub(opened)
+		if d < 0 {
+			d = 0
+		}
+		if *step == nil || d < **step {
+			*step = &d
+		}
+	}
+
+	for _, r := range reviews {
+		login := r.GetUser().GetLogin()
+		if login == author || m.Config.IsBot(login) || r.GetState() == "PENDING" || r.SubmittedAt == nil {
+			continue
+		}
+		record(&t.FirstReview, r.SubmittedAt)
+		if r.GetState() == "APPROVED" {
+			record(&t.Approval, r.SubmittedAt)
+		}
+		if !team[login] {
+			continue
+		}
+		record(&t.FirstResponse, r.SubmittedAt)
+		if _, ok := t.Reviews[login]; !ok {
+			t.Reviews[login] = r.SubmittedAt.Sub(requestedAt(events, login, opened, *r.SubmittedAt))
+		}
+	}
+
+	for _, e := range events {
+		login := e.Actor.GetLogin()
+		if e.Event == "merged" {
+			record(&t.Merge, e.CreatedAt)
+		}
+		if team[login] && login != author && containsString(responseEvents, e.Event) {
+			record(&t.FirstResponse, e.CreatedAt)
+		}
+	}
+	return t
+}
+
+// requestedAt is when login's review was last requested before they reviewed
+// at, or opened if it never was
+func requestedAt(events []*issueEvent, login string, opened, at time.Time) time.Time {
+	requested := opened
+	for _, e := range events {
+		if e.Event != "review_requested" || e.CreatedAt == nil || e.RequestedReviewer.GetLogin() != login {
+			continue
+		}
+		if e.CreatedAt.After(requested) && e.CreatedAt.Before(at) {
+			requested = *e.CreatedAt
+		}
+	}
+	return requested
+}
+
+// durationStats summarizes how long a step took over Count PRs
+type durationStats struct {
+	Count  int
+	Median time.Duration
+	P90    time.Duration
+}
+
+func newDurationStats(ds []time.Duration) durationStats {
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+	return durationStats{
+		Count:  len(ds),
+		Median: percentile(ds, 50),
+		P90:    percentile(ds, 90),
+	}
+}
+
+// percentile is the nearest rank p percentile of the sorted ds
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(ds))))
+	if rank < 1 {
+		rank = 1
+	}
+	return ds[rank-1]
+}
+
+// String is the table cell of s, ex. "5h / 3d 2h"
+func (s durationStats) String() string {
+	if s.Count == 0 {
+		return "-"
+	}
+	return formatAge(s.Median) + " / " + formatAge(s.P90)
+}
+
+// hours is the csv and json form of a duration
+func hours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 1, 64)
+}
+
+func (s durationStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Count  int              `json:"count"`
+		Median *json.RawMessage `json:"median_hours"`
+		P90    *json.RawMessage `json:"p90_hours"`
+	}{
+		Count:  s.Count,
+		Median: s.rawHours(s.Median),
+		P90:    s.rawHours(s.P90),
+	})
+}
+
+// rawHours is d in hours for MarshalJSON, or null when nothing was measured
+func (s durationStats) rawHours(d time.Duration) *json.RawMessage {
+	if s.Count == 0 {
+		return nil
+	}
+	raw := json.RawMessage(hours(d))
+	return &raw
+}
+
+// repoMetrics is the review turnaround of a repository's PRs
+type repoMetrics struct {
+	Repo          string        `json:"repo"`
+	PullRequests  int           `json:"pull_requests"`
+	FirstReview   durationStats `json:"time_to_first_review"`
+	FirstResponse durationStats `json:"time_to_first_team_response"`
+	Approval      durationStats `json:"time_to_approval"`
+	Merge         durationStats `json:"time_to_merge"`
+}
+
+func newRepoMetrics(repo string, ts []*prTurnaround) *repoMetrics {
+	var review, response, approval, merge []time.Duration
+	add := func(ds *[]time.Duration, d *time.Duration) {
+		if d != nil {
+			*ds = append(*ds, *d)
+		}
+	}
+	for _, t := range ts {
+		add(&review, t.FirstReview)
+		add(&response, t.FirstResponse)
+		add(&approval, t.Approval)
+		add(&merge, t.Merge)
+	}
+	return &repoMetrics{
+		Repo:          repo,
+		PullRequests:  len(ts),
+		FirstReview:   newDurationStats(review),
+		FirstResponse: newDurationStats(response),
+		Approval:      newDurationStats(approval),
+		Merge:         newDurationStats(merge),
+	}
+}
+
+// reviewerMetrics is how fast a team member reviews
+type reviewerMetrics struct {
+	Login        string        `json:"login"`
+	Reviews      int           `json:"reviews"`
+	TimeToReview durationStats `json:"time_to_review"`
+}
+
+// reviewMetricsOutput is the result of `tfteam metrics reviews`. All is the
+// turnaround over every repository.
+type reviewMetricsOutput struct {
+	Since     time.Time          `json:"since"`
+	All       *repoMetrics       `json:"all"`
+	Repos     []*repoMetrics     `json:"repos"`
+	Reviewers []*reviewerMetrics `json:"reviewers"`
+}
+
+func newReviewMetrics(since time.Time, ts []*prTurnaround) *reviewMetricsOutput {
+	byRepo := make(map[string][]*prTurnaround)
+	byReviewer := make(map[string][]time.Duration)
+	for _, t := range ts {
+		byRepo[t.Repo] = append(byRepo[t.Repo], t)
+		for login, d := range t.Reviews {
+			byReviewer[login] = append(byReviewer[login], d)
+		}
+	}
+
+	out := &reviewMetricsOutput{
+		Since:     since,
+		All:       newRepoMetrics("all", ts),
+		Repos:     []*repoMetrics{},
+		Reviewers: []*reviewerMetrics{},
+	}
+	for repo, rts := range byRepo {
+		out.Repos = append(out.Repos, newRepoMetrics(repo, rts))
+	}
+	sort.Slice(out.Repos, func(i, j int) bool { return out.Repos[i].Repo < out.Repos[j].Repo })
+
+	for login, ds := range byReviewer {
+		out.Reviewers = append(out.Reviewers, &reviewerMetrics{
+			Login:        login,
+			Reviews:      len(ds),
+			TimeToReview: newDurationStats(ds),
+		})
+	}
+	sort.Slice(out.Reviewers, func(i, j int) bool {
+		a, b := out.Reviewers[i], out.Reviewers[j]
+		if a.Reviews != b.Reviews {
+			return a.Reviews > b.Reviews
+		}
+		return a.Login < b.Login
+	})
+	return out
+}
+
+// Header is one row per measure, so repos and reviewers share the columns
+func (o *reviewMetricsOutput) Header() []string {
+	return []string{"by", "name", "measure", "count", "median_hours", "p90_hours"}
+}
+
+func (o *reviewMetricsOutput) Rows() [][]string {
+	var rows [][]string
+	row := func(by, name, measure string, s durationStats) {
+		median, p90 := "", ""
+		if s.Count > 0 {
+			median, p90 = hours(s.Median), hours(s.P90)
+		}
+		rows = append(rows, []string{by, name, measure, strconv.Itoa(s.Count), median, p90})
+	}
+	for _, r := range append([]*repoMetrics{o.All}, o.Repos...) {
+		by := "repo"
+		if r == o.All {
+			by = "all"
+		}
+		row(by, r.Repo, "first_review", r.FirstReview)
+		row(by, r.Repo, "first_team_response", r.FirstResponse)
+		row(by, r.Repo, "approval", r.Approval)
+		row(by, r.Repo, "merge", r.Merge)
+	}
+	for _, r := range o.Reviewers {
+		row("reviewer", r.Login, "review", r.TimeToReview)
+	}
+	return rows
+}
+
+func (o *reviewMetricsOutput) Table(out io.Writer) {
+	fmt.Fprintf(out, "Review turnaround of %d pull requests closed since %s (median / p90)\n\n", o.All.PullRequests, o.Since.Format("Mon 01/02/2006"))
+	if o.All.PullRequests == 0 {
+		return
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Repo\tPRs\tFirst review\tFirst team response\tApproval\tMerge")
+	for _, r := range append([]*repoMetrics{o.All}, o.Repos...) {
+		name := r.Repo
+		if r == o.All {
+			name = "All"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", name, r.PullRequests, r.FirstReview, r.FirstResponse, r.Approval, r.Merge)
+	}
+	w.Flush()
+
+	if len(o.Reviewers) == 0 {
+		return
+	}
+	fmt.Fprintln(out)
+	w.Init(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "Reviewer\tReviews\tTime to review")
+	for _, r := range o.Reviewers {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", r.Login, r.Reviews, r.TimeToReview)
+	}
+	w.Flush()
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePeriod(t *testing.T) {
+	day := 24 * time.Hour
+
+	cases := []struct {
+		Period   string
+		Duration time.Duration
+		Err      string
+	}{
+		{"7d", 7 * day, ""},
+		{"2w", 14 * day, ""},
+		{"1w", 7 * day, ""},
+		{"30d", 30 * day, ""},
+		{"36h", 36 * time.Hour, ""},
+		{"0d", 0, "must be positive"},
+		{"-1w", 0, "must be positive"},
+		{"-2h", 0, "must be positive"},
+		{"", 0, "is not a period"},
+		{"d", 0, "is not a period"},
+		{"1.5d", 0, "is not a period"},
+		{"2 weeks", 0, "is not a period"},
+		{"7x", 0, "is not a period"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Period, func(t *testing.T) {
+			d, err := parsePeriod(tc.Period)
+			if tc.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Err) {
+					t.Fatalf("expected error containing %q, got %v", tc.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if d != tc.Duration {
+				t.Fatalf("expected %s, got %s", tc.Duration, d)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	// hours returns the sorted durations 1h to nh
+	hours := func(n int) []time.Duration {
+		var ds []time.Duration
+		for i := 1; i <= n; i++ {
+			ds = append(ds, time.Duration(i)*time.Hour)
+		}
+		return ds
+	}
+
+	cases := []struct {
+		Name     string
+		Samples  []time.Duration
+		P        float64
+		Expected time.Duration
+	}{
+		{"no samples", nil, 50, 0},
+		{"one sample, median", hours(1), 50, time.Hour},
+		{"one sample, p90", hours(1), 90, time.Hour},
+		{"one sample, p0", hours(1), 0, time.Hour},
+		{"three samples, median", hours(3), 50, 2 * time.Hour},
+		{"three samples, p90", hours(3), 90, 3 * time.Hour},
+		{"ten samples, median", hours(10), 50, 5 * time.Hour},
+		{"ten samples, p90", hours(10), 90, 9 * time.Hour},
+		{"ten samples, p91", hours(10), 91, 10 * time.Hour},
+		{"ten samples, p0", hours(10), 0, time.Hour},
+		{"ten samples, p100", hours(10), 100, 10 * time.Hour},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			actual := percentile(tc.Samples, tc.P)
+			if actual != tc.Expected {
+				t.Fatalf("expected %s, got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -2,25 +2,20 @@ package commands
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/url"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
-
-	"golang.org/x/oauth2"
 
 	"github.com/google/go-github/github"
-	"github.com/mitchellh/cli"
 )
 
-var wgNIssues sync.WaitGroup
-
 type NotificationsCommand struct {
-	UI cli.Ui
+	Meta
 }
 
 func (c NotificationsCommand) Help() string {
@@ -30,12 +25,22 @@ Usage: tfteam notifications [options]
 	Aggregate GitHub notifications for Terraform* repositories, filtering out
 	notifications that have a reply from a HashiCorp colleague
 
-Options:
-
-	--cleanup        Mark all issues and prs as 'read' if they are closed. 
-                         Considers merged prs as closed.
+%s
 `
-	return helpText
+	return fmt.Sprintf(helpText, flagsHelp(c.flags(&notificationsOptions{})))
+}
+
+// notificationsOptions are the flags accepted by `tfteam notifications`
+type notificationsOptions struct {
+	cleanup bool
+	dryRun  bool
+}
+
+func (c *NotificationsCommand) flags(o *notificationsOptions) *flag.FlagSet {
+	f := c.flagSet("notifications")
+	boolVar(f, &o.cleanup, "cleanup", "", "Mark all issues and prs as 'read' if they are closed. Considers merged prs as closed.")
+	boolVar(f, &o.dryRun, "dry-run", "", "With --cleanup, show what would be marked as 'read' without changing anything")
+	return f
 }
 
 func (c NotificationsCommand) Synopsis() string {
@@ -47,74 +52,80 @@ Aggregate GitHub notifications for Terraform* repositories, filtering out
 }
 
 type NotificationIssue struct {
-	ID        string
-	Owner     string
-	Name      string
-	Number    int
-	Title     string
-	URL       string
-	Reviewed  bool
-	Closed    bool
-	IsRelease bool
+	ID        string `json:"id"`
+	Owner     string `json:"owner"`
+	Name      string `json:"repo"`
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	URL       string `json:"api_url"`
+	Reviewed  bool   `json:"reviewed"`
+	Closed    bool   `json:"closed"`
+	IsRelease bool   `json:"is_release"`
 }
 
 func (n *NotificationIssue) String() string {
-	return fmt.Sprintf("%s - https://github.com/%s/%s/issues/%d", n.Title, n.Owner, n.Name, n.Number)
+	return fmt.Sprintf("%s - %s", n.Title, n.HTMLURL())
+}
+
+// HTMLURL is the link to the issue or pull request on github.com. GitHub
+// redirects /issues/ links for pull requests.
+func (n *NotificationIssue) HTMLURL() string {
+	return fmt.Sprintf("https://github.com/%s/%s/issues/%d", n.Owner, n.Name, n.Number)
 }
 
 func (n *NotificationIssue) Repo() string {
 	return fmt.Sprintf("%s/%s", n.Owner, n.Name)
 }
 
-type ByNumber []*NotificationIssue
+// ByRepoNumber sorts notifications by repo, then by issue number
+type ByRepoNumber []*NotificationIssue
 
-func (a ByNumber) Len() int      { return len(a) }
-func (a ByNumber) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
-func (a ByNumber) Less(i, j int) bool {
+func (a ByRepoNumber) Len() int      { return len(a) }
+func (a ByRepoNumber) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByRepoNumber) Less(i, j int) bool {
+	if a[i].Repo() != a[j].Repo() {
+		return a[i].Repo() < a[j].Repo()
+	}
 	return a[i].Number < a[j].Number
 }
 
 func (c NotificationsCommand) Run(args []string) int {
-	key := os.Getenv("GITHUB_API_TOKEN")
-	if key == "" {
-		c.UI.Error("Missing API Token!")
+	var opts notificationsOptions
+	if err := c.process(c.flags(&opts), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
 		return 1
 	}
 
-	// refactor, this is boilerplate
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 
-	client := github.NewClient(tc)
+	ctx, cancel := c.context()
+	defer cancel()
 
 	// github.NotificationListOptions has useful attributes but for now we'll just
 	// do defauls
 	nopt := &github.NotificationListOptions{}
 	var notifications []*github.Notification
-	for {
+	err = paginate(&nopt.ListOptions, c.Config.GitHub.PerPage, func() (*github.Response, error) {
 		part, resp, err := client.Activity.ListNotifications(ctx, nopt)
-		if err != nil {
-			c.UI.Warn(fmt.Sprintf("Error listing notifications: %s", err))
-			return 1
-		}
 		notifications = append(notifications, part...)
-		if resp.NextPage == 0 {
-			break
-		}
-		nopt.Page = resp.NextPage
+		return resp, err
+	})
+	if err != nil {
+		// carry on with the pages we got
+		c.fail("notifications", fmt.Errorf("error listing notifications: %s", err))
 	}
 
 	// NotificationIssues to look for
 	var nIssues []*NotificationIssue
 	// Filter out PRs/Issues that aren't involving Terraform
 	for _, n := range notifications {
-		if !strings.Contains(*n.Repository.Name, "terraform") {
-			if !strings.Contains(*n.Repository.Name, "tfteam") {
-				continue
-			}
+		if !c.Config.MatchesNotificationRepo(*n.Repository.Name) {
+			continue
 		}
 
 		// We don't currently check private repos
@@ -160,105 +171,107 @@ func (c NotificationsCommand) Run(args []string) int {
 		nIssues = append(nIssues, &ni)
 	}
 
-	// 5 "workers" to do things concurrently
-	wCount := 5
-	wgNIssues.Add(wCount)
-
-	// queue of NotificationIssues to query on the review status
-	niChan := make(chan *NotificationIssue, len(nIssues))
-
-	// recieve results from PR review queries
-	resultsChan := make(chan *NotificationIssue, len(nIssues))
+	// mark things as viewed when cleaning up, otherwise look up the review
+	// status, concurrently
+	teamMembers := c.Config.TeamMembers()
+	errs := runPool(ctx, c.concurrency, len(nIssues), func(ctx context.Context, i int) error {
+		if opts.cleanup {
+			return markReadIfClosed(ctx, client, nIssues[i], opts.dryRun)
+		}
+		return getReviewStatus(ctx, client, c.Config.GitHub.PerPage, teamMembers, nIssues[i])
+	})
 
-	// figure out what kind of work we're doing by looking for any flags
-	// hacky because it only reads the first arg, but probably ugly for other
-	// reasons too
-	var action string
-	var modifier string
-	if len(args) > 0 {
-		action = args[0]
+	out := &notificationsOutput{
+		Cleanup: opts.cleanup,
+		DryRun:  opts.dryRun,
 	}
-	if len(args) > 1 {
-		modifier = args[1]
-	}
-	if "--cleanup" == action {
-		dryOutput := ""
-		if modifier != "" {
-			dryOutput = " - dry run"
+	// range over the results we get. If there is no review, add the
+	// NotificationIssue to the output. When cleaning up, only show the ones that
+	// were closed
+	checked := 0
+	for i, r := range nIssues {
+		if errs[i] != nil {
+			c.fail(r.String(), errs[i])
+			continue
 		}
-		c.UI.Output("------")
-		c.UI.Output(fmt.Sprintf("%s%s", "Notifications cleanup", dryOutput))
-		c.UI.Output("------")
-		c.UI.Output("")
-
-		// Setup go() workers to mark things as viewed
-		dryRun := "--dry-run" == modifier
-		for gr := 1; gr <= wCount; gr++ {
-			go markReadIfClosed(niChan, resultsChan, dryRun)
+		checked++
+		if r.Reviewed {
+			continue
 		}
-	} else {
-		c.UI.Output("------")
-		c.UI.Output("Notifications that have no TF Team Member comment")
-		c.UI.Output("------")
-		c.UI.Output("")
-
-		// Setup go() workers for review status, the default
-		for gr := 1; gr <= wCount; gr++ {
-			go getReviewStatus(niChan, resultsChan)
+		if opts.cleanup && !r.Closed {
+			continue
 		}
+		out.Notifications = append(out.Notifications, r)
 	}
+	// sort by repo in Alpha order for consistent output, then sub sort the
+	// issues/prs by their number
+	sort.Sort(ByRepoNumber(out.Notifications))
 
-	// Feed PRs into the queue
-	for _, i := range nIssues {
-		niChan <- i
+	if err := c.render(out); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
 	}
 
-	close(niChan)
-	wgNIssues.Wait()
-	close(resultsChan)
+	return c.exitStatus(checked)
+}
 
-	repoIssueMap := make(map[string][]*NotificationIssue)
-	// range over the results we get. If there is no review, add the
-	// NotificationIssue to the repoIssueMap based on its Repo
-	for r := range resultsChan {
-		if !r.Reviewed {
-			repoIssueMap[r.Repo()] = append(repoIssueMap[r.Repo()], r)
-		}
-	}
+// notificationsOutput is the result of `tfteam notifications`
+type notificationsOutput struct {
+	Cleanup       bool                 `json:"cleanup"`
+	DryRun        bool                 `json:"dry_run"`
+	Notifications []*NotificationIssue `json:"notifications"`
+}
+
+func (o *notificationsOutput) Header() []string {
+	return []string{"id", "owner", "repo", "number", "title", "url", "closed", "release"}
+}
 
-	// sort repoIssueMap by Alpha order for consistent
-	var keys []string
-	for k, _ := range repoIssueMap {
-		keys = append(keys, k)
+func (o *notificationsOutput) Rows() [][]string {
+	var rows [][]string
+	for _, n := range o.Notifications {
+		rows = append(rows, []string{
+			n.ID,
+			n.Owner,
+			n.Name,
+			strconv.Itoa(n.Number),
+			n.Title,
+			n.HTMLURL(),
+			strconv.FormatBool(n.Closed),
+			strconv.FormatBool(n.IsRelease),
+		})
 	}
+	return rows
+}
 
-	sort.Strings(keys)
-
-	var count int
-	for _, k := range keys {
-		niList := repoIssueMap[k]
-		// omit any repos that have zero things needing review
-		display := niList
-		if "--cleanup" == action {
-			display = nil
-			for _, i := range niList {
-				if i.Closed {
-					display = append(display, i)
-				}
-			}
+func (o *notificationsOutput) Table(w io.Writer) {
+	fmt.Fprintln(w, "------")
+	if o.Cleanup {
+		dryOutput := ""
+		if o.DryRun {
+			dryOutput = " - dry run"
 		}
-		if len(display) > 0 {
-			c.UI.Output(k)
-			// sub sort the issues/prs by their number
-			sort.Sort(ByNumber(display))
-			count += len(display)
-			for _, i := range display {
-				c.UI.Output(fmt.Sprintf("  - %s", i.String()))
+		fmt.Fprintf(w, "%s%s\n", "Notifications cleanup", dryOutput)
+	} else {
+		fmt.Fprintln(w, "Notifications that have no TF Team Member comment")
+	}
+	fmt.Fprintln(w, "------")
+	fmt.Fprintln(w)
+
+	var last string
+	for _, n := range o.Notifications {
+		if n.Repo() != last {
+			if last != "" {
+				fmt.Fprintln(w)
 			}
-			c.UI.Output("")
+			fmt.Fprintln(w, n.Repo())
+			last = n.Repo()
 		}
+		fmt.Fprintf(w, "  - %s\n", n.String())
+	}
+	if last != "" {
+		fmt.Fprintln(w)
 	}
-	c.UI.Output(fmt.Sprintf("Total count: %d", count))
+	fmt.Fprintf(w, "Total count: %d\n", len(o.Notifications))
 
 	// exercise for tomorrow: tab format the output
 	// w := new(tabwriter.Writer)
@@ -270,100 +283,60 @@ func (c NotificationsCommand) Run(args []string) int {
 	// fmt.Fprintln(w, "123\t12345\t1234567\t123456789\t.")
 	// fmt.Fprintln(w)
 	// w.Flush()
-
-	return 0
 }
 
-func getReviewStatus(notificationsChan <-chan *NotificationIssue, rChan chan<- *NotificationIssue) {
-	defer wgNIssues.Done()
-	// should pass in and reususe context I think?
-	key := os.Getenv("GITHUB_API_TOKEN")
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-
-	client := github.NewClient(tc)
-
-	for n := range notificationsChan {
-		if !n.IsRelease {
-			comments, _, err := client.Issues.ListComments(ctx, n.Owner, n.Name, n.Number, nil)
-			if err != nil {
-				log.Printf("error getting comments for (%s): %s", n.String(), err)
-				// could error if it's a private repo; right now we are scoped to only
-				// public things
-				continue
-			}
-
-			if len(comments) == 0 {
-				continue
-			}
+// getReviewStatus sets n.Reviewed if someone on the team commented on it
+func getReviewStatus(ctx context.Context, client *github.Client, perPage int, teamMembers []string, n *NotificationIssue) error {
+	if n.IsRelease {
+		return nil
+	}
 
-			// should be dynamic but I'm lazy at this particular moment
-			teamMembers := []string{
-				"mitchellh",
-				"apparentlymart",
-				"jbardin",
-				"phinze",
-				"paddycarver",
-				"catsby",
-				"radeksimko",
-				"tombuildsstuff",
-				"grubernaut",
-				"mbfrahry",
-				"vancluever",
-			}
+	var comments []*github.IssueComment
+	copt := &github.IssueListCommentsOptions{}
+	err := paginate(&copt.ListOptions, perPage, func() (*github.Response, error) {
+		part, resp, err := client.Issues.ListComments(ctx, n.Owner, n.Name, n.Number, copt)
+		comments = append(comments, part...)
+		return resp, err
+	})
+	if err != nil {
+		// could error if it's a private repo; right now we are scoped to only
+		// public things
+		return fmt.Errorf("error getting comments: %s", err)
+	}
 
-			for _, comment := range comments {
-				for _, m := range teamMembers {
-					if m == *comment.User.Login {
-						n.Reviewed = true
-						continue
-					}
-				}
-			}
+	for _, comment := range comments {
+		if containsString(teamMembers, *comment.User.Login) {
+			n.Reviewed = true
+			break
 		}
-		rChan <- n
 	}
+	return nil
 }
 
 // Function that marks closed issues/prs as "read"
-func markReadIfClosed(notificationsChan <-chan *NotificationIssue, rChan chan<- *NotificationIssue, dryRun bool) {
-	defer wgNIssues.Done()
-	// should pass in and reususe context I think?
-	key := os.Getenv("GITHUB_API_TOKEN")
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-
-	client := github.NewClient(tc)
-
-	for n := range notificationsChan {
-		issue, _, err := client.Issues.Get(ctx, n.Owner, n.Name, n.Number)
-		if err != nil {
-			// Error could be a glitch or the source could be private and right now
-			// the tool is only scoped for public things
-			continue
-		}
+func markReadIfClosed(ctx context.Context, client *github.Client, n *NotificationIssue, dryRun bool) error {
+	if n.IsRelease {
+		// releases don't close
+		return nil
+	}
 
-		// log.Printf("issue state for (%s): %s", n.String(), *issue.State)
-		if "closed" == *issue.State {
-			if !dryRun {
-				_, err := client.Activity.MarkThreadRead(ctx, n.ID)
-				if err != nil {
-					log.Printf("Error marking (%s) Thread (%s) as read: %s", n.String(), n.ID, err)
-				} else {
-					n.Closed = true
-				}
-			} else {
-				// hack - mark it closed so we see the review of what we would close
-				n.Closed = true
+	issue, _, err := client.Issues.Get(ctx, n.Owner, n.Name, n.Number)
+	if err != nil {
+		// Error could be a glitch or the source could be private and right now
+		// the tool is only scoped for public things
+		return err
+	}
+
+	// log.Printf("issue state for (%s): %s", n.String(), *issue.State)
+	if "closed" == *issue.State {
+		if !dryRun {
+			if _, err := client.Activity.MarkThreadRead(ctx, n.ID); err != nil {
+				return fmt.Errorf("error marking thread %s as read: %s", n.ID, err)
 			}
 		}
-
-		rChan <- n
+		// when it's a dry run, mark it closed anyway so we see the review of
+		// what we would close
+		n.Closed = true
 	}
+	return nil
 }
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// Output is implemented by the results of each command so they can be
+// rendered in any of the --format options
+type Output interface {
+	// Header names the columns used by the csv and markdown formats
+	Header() []string
+
+	// Rows returns one record per result, in the same order as Header
+	Rows() [][]string
+
+	// Table writes the human readable layout
+	Table(w io.Writer)
+}
+
+// render writes out in the --format the user asked for, followed by the
+// failures recorded with fail. The json format marshals out itself, so its
+// fields and tags are the stable schema, plus a "failures" list when there are
+// any.
+func (m *Meta) render(out Output) error {
+	failures := m.failureList()
+
+	var buf bytes.Buffer
+	switch m.format {
+	case "json":
+		raw, err := json.Marshal(out)
+		if err != nil {
+			return err
+		}
+		if raw, err = appendFailures(raw, failures); err != nil {
+			return err
+		}
+		if err := json.Indent(&buf, raw, "", "  "); err != nil {
+			return err
+		}
+	case "csv":
+		w := csv.NewWriter(&buf)
+		w.Write(out.Header())
+		w.WriteAll(out.Rows())
+		if err := w.Error(); err != nil {
+			return err
+		}
+	case "markdown":
+		writeMarkdown(&buf, out.Header(), out.Rows())
+		writeFailures(&buf, failures, true)
+	default:
+		out.Table(&buf)
+		writeFailures(&buf, failures, false)
+	}
+
+	m.UI.Output(strings.TrimRight(buf.String(), "\n"))
+	if m.format == "csv" {
+		// keep stdout parseable
+		for _, err := range failures {
+			m.UI.Error(err.Error())
+		}
+	}
+	m.warnIncomplete()
+	m.showBudget()
+	return nil
+}
+
+// showBudget prints the remaining rate limit quota after the human readable
+// output, and to the debug log for the other formats
+func (m *Meta) showBudget() {
+	if m.budget == nil {
+		return
+	}
+	s := m.budget.String()
+	if s == "" {
+		return
+	}
+	switch {
+	case m.format == "table":
+		m.UI.Info("")
+		m.UI.Info(s)
+	case m.debug:
+		log.Printf("[DEBUG] %s", s)
+	}
+}
+
+// appendFailures adds a "failures" key to the json object in raw
+func appendFailures(raw []byte, failures []error) ([]byte, error) {
+	if len(failures) == 0 || len(raw) < 2 || raw[0] != '{' {
+		return raw, nil
+	}
+	f, err := json.Marshal(failures)
+	if err != nil {
+		return nil, err
+	}
+	result := append([]byte(nil), raw[:len(raw)-1]...)
+	if len(raw) > 2 {
+		result = append(result, ',')
+	}
+	result = append(result, `"failures":`...)
+	result = append(result, f...)
+	return append(result, '}'), nil
+}
+
+// writeMarkdown writes a GitHub flavored markdown table
+func writeMarkdown(w io.Writer, header []string, rows [][]string) {
+	line := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = strings.Replace(c, "|", "\\|", -1)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+	}
+
+	line(header)
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	line(sep)
+	for _, r := range rows {
+		line(r)
+	}
+}
+
+// formatTime formats optional times for the csv and markdown formats
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatAge formats a duration in days and hours, ex. "3d 4h", or "< 1h"
+func formatAge(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return "< 1h"
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"github.com/google/go-github/github"
+)
+
+// defaultPerPage is the page size used when the config doesn't set
+// github.per_page. 100 is the most GitHub allows.
+const defaultPerPage = 100
+
+// paginate calls list once per page until GitHub reports there are no more.
+// list makes the request with opt, which is the ListOptions embedded in the
+// call's options, and collects the page's results itself:
+//
+//	opt := &github.ListOptions{}
+//	err := paginate(opt, perPage, func() (*github.Response, error) {
+//		part, resp, err := client.Repositories.ListTags(ctx, owner, name, opt)
+//		tags = append(tags, part...)
+//		return resp, err
+//	})
+func paginate(opt *github.ListOptions, perPage int, list func() (*github.Response, error)) error {
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	opt.PerPage = perPage
+	opt.Page = 0
+	for {
+		resp, err := list()
+		if err != nil {
+			return err
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"sync"
+)
+
+// runPool calls fn for every index in [0, n), running at most workers of them
+// at once. fn stores its result in the caller's slice at index i, so results
+// stay in input order, and runPool returns fn's errors the same way: errs[i]
+// is nil if item i succeeded.
+//
+// Once ctx is done the remaining items aren't started, and get ctx's error.
+func runPool(ctx context.Context, workers, n int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = fn(ctx, i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return errs
+}
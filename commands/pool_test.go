@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRunPool(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Workers int
+		N       int
+		Fail    []int
+		// CancelAt cancels ctx while running that item, if not -1
+		CancelAt int
+		// Errs are the expected errors, "" for nil, and Called the items fn
+		// is called for
+		Errs   []string
+		Called []int
+	}{
+		{"no items", 4, 0, nil, -1, []string{}, nil},
+		{"no errors", 4, 3, nil, -1, []string{"", "", ""}, []int{0, 1, 2}},
+		{
+			"errors in input order",
+			3, 6, []int{4, 1, 5}, -1,
+			[]string{"", "item 1", "", "", "item 4", "item 5"},
+			[]int{0, 1, 2, 3, 4, 5},
+		},
+		{"zero workers", 0, 2, []int{0}, -1, []string{"item 0", ""}, []int{0, 1}},
+		{"more workers than items", 10, 2, []int{1}, -1, []string{"", "item 1"}, []int{0, 1}},
+		{
+			"cancelled",
+			1, 4, nil, 1,
+			[]string{"", "", context.Canceled.Error(), context.Canceled.Error()},
+			[]int{0, 1},
+		},
+		{
+			"cancelled by a failing item",
+			1, 3, []int{0}, 0,
+			[]string{"item 0", context.Canceled.Error(), context.Canceled.Error()},
+			[]int{0},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var mu sync.Mutex
+			called := make([]bool, tc.N)
+			errs := runPool(ctx, tc.Workers, tc.N, func(ctx context.Context, i int) error {
+				mu.Lock()
+				called[i] = true
+				mu.Unlock()
+				if i == tc.CancelAt {
+					cancel()
+				}
+				if containsInt(tc.Fail, i) {
+					return fmt.Errorf("item %d", i)
+				}
+				return nil
+			})
+
+			actual := []string{}
+			for _, err := range errs {
+				if err == nil {
+					actual = append(actual, "")
+				} else {
+					actual = append(actual, err.Error())
+				}
+			}
+			if !reflect.DeepEqual(actual, tc.Errs) {
+				t.Fatalf("expected errors %q, got %q", tc.Errs, actual)
+			}
+
+			var calledItems []int
+			for i, c := range called {
+				if c {
+					calledItems = append(calledItems, i)
+				}
+			}
+			if !reflect.DeepEqual(calledItems, tc.Called) {
+				t.Fatalf("expected fn called for %v, got %v", tc.Called, calledItems)
+			}
+		})
+	}
+}
+
+func containsInt(s []int, i int) bool {
+	for _, v := range s {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-github/github"
+)
+
+// PRCheckCommand checks a provider PR adds what every new resource needs
+type PRCheckCommand struct {
+	Meta
+}
+
+func (c PRCheckCommand) Help() string {
+	helpText := `
+Usage: tfteam pr-check [options] <pr-url>
+
+	Check that every resource and data source a provider pull request adds
+	comes with acceptance tests, a docs page and a sidebar entry. The pull
+	request is given as its URL or as owner/repo#number.
+
+	A new aws/resource_aws_foo.go needs, in the same pull request:
+
+	  - aws/resource_aws_foo_test.go
+	  - website/docs/r/foo.html.markdown (d/ for data sources)
+	  - a link to that page added to a website/*.erb sidebar
+
+%s
+
+Examples:
+
+  $ tfteam pr-check terraform-providers/terraform-provider-aws#1629
+  terraform-providers/terraform-provider-aws#1629: 1 new resource, 1 incomplete
+
+  aws_foo (aws/resource_aws_foo.go)
+    [x] tests      aws/resource_aws_foo_test.go
+    [ ] docs       website/docs/r/foo.html.markdown
+    [ ] sidebar    website/aws.erb
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flagSet("pr-check"))))
+}
+
+func (c PRCheckCommand) Synopsis() string {
+	return "Check a provider PR's new resources have tests, docs and a sidebar entry"
+}
+
+func (c PRCheckCommand) Run(args []string) int {
+	positional, err := c.processArgs(c.flagSet("pr-check"), args, "pr-url")
+	if err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
+		return 1
+	}
+	owner, repo, number, err := parsePRURL(positional[0])
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	files, err := listPRFiles(ctx, client, c.Config.GitHub.PerPage, owner, repo, number)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error listing files of %s/%s#%d: %s", owner, repo, number, err))
+		return 1
+	}
+
+	out := &prCheckOutput{
+		PR:     fmt.Sprintf("%s/%s#%d", owner, repo, number),
+		Checks: checkProviderFiles(files),
+	}
+	if err := c.render(out); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
+	}
+	return c.exitStatus(1)
+}
+
+// providerCheck is a resource or data source a PR adds, and the files that
+// complete it. Test, Docs and Sidebar are empty when the PR doesn't have
+// them.
+type providerCheck struct {
+	File    string `json:"file"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Test    string `json:"test"`
+	Docs    string `json:"docs"`
+	Sidebar string `json:"sidebar"`
+
+	// the files we looked for, for the pr-check report
+	wantTest    string
+	wantDocs    string
+	wantSidebar string
+}
+
+// Missing names what the resource lacks: tests, docs or sidebar
+func (p *providerCheck) Missing() []string {
+	var missing []string
+	if p.Test == "" {
+		missing = append(missing, "tests")
+	}
+	if p.Docs == "" {
+		missing = append(missing, "docs")
+	}
+	if p.Sidebar == "" {
+		missing = append(missing, "sidebar")
+	}
+	return missing
+}
+
+// checkProviderFiles finds the resource_*.go and data_source_*.go files the
+// PR adds, and checks the PR also has their tests, docs page and a sidebar
+// link to it
+func checkProviderFiles(files []*github.CommitFile) []*providerCheck {
+	checks := []*providerCheck{}
+	for _, f := range files {
+		name := f.GetFilename()
+		dir, base := path.Split(name)
+		if f.GetStatus() != "added" || !strings.HasSuffix(base, ".go") || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+
+		var kind, docsDir string
+		switch {
+		case strings.HasPrefix(base, "resource_"):
+			kind, docsDir = "resource", "r"
+		case strings.HasPrefix(base, "data_source_"):
+			kind, docsDir = "data_source", "d"
+		default:
+			continue
+		}
+
+		// aws/resource_aws_foo.go is aws_foo, documented as foo
+		full := strings.TrimSuffix(strings.TrimPrefix(base, kind+"_"), ".go")
+		provider := path.Base(dir)
+		short := strings.TrimPrefix(full, provider+"_")
+
+		check := &providerCheck{
+			File:        name,
+			Kind:        kind,
+			Name:        full,
+			wantTest:    strings.TrimSuffix(name, ".go") + "_test.go",
+			wantDocs:    fmt.Sprintf("website/docs/%s/%s.html.markdown", docsDir, short),
+			wantSidebar: fmt.Sprintf("website/%s.erb", provider),
+		}
+		for _, other := range files {
+			o := other.GetFilename()
+			switch {
+			case o == check.wantTest:
+				check.Test = o
+			case path.Dir(o) == "website/docs/"+docsDir && docsPageName(path.Base(o)) == short:
+				check.Docs = o
+			case path.Dir(o) == "website" && path.Ext(o) == ".erb" && addsLink(other.GetPatch(), docsDir+"/"+short+".html"):
+				check.Sidebar = o
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// docsPageName is the name a docs page documents, ex. foo for
+// foo.html.markdown
+func docsPageName(base string) string {
+	for _, ext := range []string{".html.markdown", ".html.md", ".markdown", ".md"} {
+		if strings.HasSuffix(base, ext) {
+			return strings.TrimSuffix(base, ext)
+		}
+	}
+	return ""
+}
+
+// addsLink is true if the patch adds a line linking to page
+func addsLink(patch, page string) bool {
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "+") && strings.Contains(line, page) {
+			return true
+		}
+	}
+	return false
+}
+
+// completenessString is the Checks column of prs, ex. "ok", or
+// "aws_foo: docs, sidebar". PRs that don't add resources show "-".
+func completenessString(checks []*providerCheck) string {
+	if len(checks) == 0 {
+		return "-"
+	}
+	var parts []string
+	for _, c := range checks {
+		if missing := c.Missing(); len(missing) > 0 {
+			parts = append(parts, c.Name+": "+strings.Join(missing, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "ok"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// prCheckOutput is the result of `tfteam pr-check`
+type prCheckOutput struct {
+	PR     string           `json:"pull_request"`
+	Checks []*providerCheck `json:"checks"`
+}
+
+func (o *prCheckOutput) Header() []string {
+	return []string{"name", "kind", "file", "test", "docs", "sidebar"}
+}
+
+func (o *prCheckOutput) Rows() [][]string {
+	var rows [][]string
+	for _, c := range o.Checks {
+		rows = append(rows, []string{c.Name, c.Kind, c.File, c.Test, c.Docs, c.Sidebar})
+	}
+	return rows
+}
+
+func (o *prCheckOutput) Table(out io.Writer) {
+	incomplete := 0
+	for _, c := range o.Checks {
+		if len(c.Missing()) > 0 {
+			incomplete++
+		}
+	}
+	noun := "resources or data sources"
+	if len(o.Checks) == 1 {
+		noun = strings.Replace(o.Checks[0].Kind, "_", " ", -1)
+	}
+	fmt.Fprintf(out, "%s: %d new %s, %d incomplete\n", o.PR, len(o.Checks), noun, incomplete)
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 1, '\t', 0)
+	for _, c := range o.Checks {
+		fmt.Fprintf(w, "\n%s (%s)\n", c.Name, c.File)
+		for _, item := range []struct {
+			name, have, want string
+		}{
+			{"tests", c.Test, c.wantTest},
+			{"docs", c.Docs, c.wantDocs},
+			{"sidebar", c.Sidebar, c.wantSidebar},
+		} {
+			mark, file := "[ ]", item.want
+			if item.have != "" {
+				mark, file = "[x]", item.have
+			}
+			fmt.Fprintf(w, "  %s %s\t%s\n", mark, item.name, file)
+		}
+	}
+	w.Flush()
+}
@@ -2,45 +2,126 @@ package commands
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/url"
-	"os"
 	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"text/tabwriter"
-
-	"golang.org/x/oauth2"
+	"time"
 
 	"github.com/google/go-github/github"
-	"github.com/mitchellh/cli"
 )
 
-var wgPrs sync.WaitGroup
-
-var collaborators bool
-var all bool
-var includeUsers []string
-var filterUsers []string
-var tableFormat bool
-
 type PRReviewStatus uint
 
-var filter PRReviewStatus
-
-const tfTeamId = 1836975
-
 const (
 	StatusAll PRReviewStatus = iota
 	StatusWaiting
 	StatusComments
 	StatusChanges
+	StatusUpdated
 	StatusApproved
 )
 
+func (s PRReviewStatus) String() string {
+	switch s {
+	case StatusWaiting:
+		return "waiting"
+	case StatusComments:
+		return "comments"
+	case StatusChanges:
+		return "changes_requested"
+	case StatusUpdated:
+		return "updated"
+	case StatusApproved:
+		return "approved"
+	}
+	return "all"
+}
+
 type PRsCommand struct {
-	UI cli.Ui
+	Meta
+}
+
+// prsOptions are the flags accepted by `tfteam prs`
+type prsOptions struct {
+	collaborators bool
+	all           bool
+	includeUsers  []string
+	filterUsers   []string
+	waiting       bool
+	rereview      bool
+	table         bool
+	ci            string
+	mergeStatus   bool
+	conflicts     bool
+	maxBehind     int
+	minSize       string
+	maxSize       string
+	excludeWIP    bool
+	onlyWIP       bool
+	groupBy       string
+	sortBy        string
+	reverse       bool
+	suggest       bool
+	codeOwners    bool
+	owner         string
+	check         bool
+
+	reviewRequested     bool
+	teamReviewRequested string
+	teams               []string
+
+	// keepExcluded keeps the PRs of ExcludedReviewers in authors, for the
+	// metrics, which cover the whole team
+	keepExcluded bool
+
+	// requestedFor is the login or org/team whose review requests we list
+	requestedFor string
+
+	// candidates are the team members we suggest reviewers from, and
+	// reviewLoad their open reviews, with --suggest-reviewers
+	candidates []string
+	reviewLoad map[string]int
+
+	// teamConfigs are the parsed --team flags, and groups the members of
+	// the teams we searched, for the by-user headings
+	teamConfigs []*TeamConfig
+	groups      []teamGroup
+}
+
+func (c *PRsCommand) flags(o *prsOptions) *flag.FlagSet {
+	f := c.flagSet("prs")
+	boolVar(f, &o.collaborators, "collaborators", "c", "Only Pull Requests from repository collaborators")
+	boolVar(f, &o.all, "all", "a", "Pull Requests from team and repository collaborators")
+	listVar(f, &o.teams, "team", "", "A comma seperated list of `org/slug` teams to list pull requests from, instead of the configured teams. Can be repeated")
+	listVar(f, &o.includeUsers, "users", "u", "A comma seperated list of `users` to include pull requests from")
+	listVar(f, &o.filterUsers, "filter", "f", "A comma seperated list of `users` to only show results for. This takes precedence over all other user modifing arguments")
+	boolVar(f, &o.waiting, "waiting", "w", "Only show pull requests that have no reviews")
+	boolVar(f, &o.rereview, "needs-rereview", "", "Only show pull requests with new commits since changes were requested")
+	boolVar(f, &o.table, "table", "t", "Show the output in a single table, sorted by repository")
+	stringVar(f, &o.groupBy, "group-by", "", "Group pull requests by `author`, repo, status, age or none. Default: author, or repo with -t")
+	stringVar(f, &o.sortBy, "sort", "", "Sort pull requests within their group by `created`, updated, repo, status or size. Default: created")
+	boolVar(f, &o.reverse, "reverse", "", "Reverse the --sort order, ex. newest first")
+	boolVar(f, &o.codeOwners, "code-owners", "", "Add a column of the users and teams owning the changed files in CODEOWNERS")
+	stringVar(f, &o.owner, "owner", "", "Only show pull requests changing files this `login` or org/team owns in CODEOWNERS, requested as a reviewer or not. Implies --code-owners")
+	boolVar(f, &o.check, "check", "", "Add a column flagging new resources and data sources without tests, docs or a sidebar entry, see `tfteam pr-check`")
+	boolVar(f, &o.suggest, "suggest-reviewers", "", "Add a column of the team members best placed to review each pull request, see `tfteam suggest-reviewers`")
+	stringVar(f, &o.ci, "ci", "", "Only show pull requests whose CI is `failing`, passing or pending")
+	boolVar(f, &o.mergeStatus, "merge-status", "m", "Show whether each pull request has conflicts, and how far behind its base branch it is")
+	boolVar(f, &o.conflicts, "conflicts", "", "Only show pull requests with conflicts or more than --max-behind commits behind their base branch. Implies --merge-status")
+	f.IntVar(&o.maxBehind, "max-behind", 50, "Number of `commits` behind the base branch after which a pull request needs a rebase")
+	stringVar(f, &o.minSize, "min-size", "", "Only show pull requests of at least this `size`: XS, S, M, L or XL")
+	stringVar(f, &o.maxSize, "max-size", "", "Only show pull requests of at most this `size`: XS, S, M, L or XL")
+	boolVar(f, &o.excludeWIP, "exclude-wip", "", "Leave out work in progress pull requests")
+	boolVar(f, &o.onlyWIP, "only-wip", "", "Only show work in progress pull requests")
+	boolVar(f, &o.reviewRequested, "review-requested", "r", "List pull requests waiting on your review, instead of by author")
+	stringVar(f, &o.teamReviewRequested, "team-review-requested", "", "List pull requests waiting on a review from this `org/team`, instead of by author")
+	return f
 }
 
 func (c PRsCommand) Help() string {
@@ -50,31 +131,43 @@ Usage: tfteam prs [options]
 	List pull requests that are opened by team members. The output includes the
 	status of the pull request, author, repo, title, and link.
 
-	Pull requests are in 1 of 4 states: 
+	Pull requests are in 1 of 5 states: 
           - " " No review 
           - "+  " Reviewed, Approved!
           - "?  " Reviewed, with Comments
           - "-  " Reviewed, with Changes requested
+          - "~  " Changes requested, but updated since: needs re-review
 
-	If no arguments are given, list just pull requests  and their status for
-	Terraform OSS team members only, grouped by user.
+	A "W" after the state marks a work in progress: a draft, a title starting
+	with "[WIP]" or "WIP:", or one of the config's wip_labels.
+
+	The CI column is the combined commit status of the head commit, with the
+	names of any failing checks. With --merge-status, the Merge column shows
+	"conflicts", or how many commits the PR is behind its base branch, marked
+	with a "!" past --max-behind.
 
-Options:
+	--group-by picks the headings PRs are listed under: their author (under
+	team headings, see below), repo, review status, or age: opened
+	today, this week, this month or more than 30 days ago. With -t the groups
+	are kept together in the one table.
 
-	--collaborators, -c        Only Pull Requests from repository collaborators 
-	
-	--all, -a                  Pull Requests from team and repository collaborators 
+	The Size column of -t buckets PRs by changed lines: XS under 10, S under
+	30, M under 100, L under 500 and XL above that.
 
-	--users, -u                A comma seperated list of users to include pull
-                             requests from 
+	With --code-owners, the users and teams owning the changed files in the
+	repository's CODEOWNERS (in the root, .github/ or docs/) are listed, and
+	--owner only shows the pull requests touching files someone owns.
 
-	--filter, -f               A comma seperated list of users to only show
-                             results for. This takes precedence over all other user modifing arguments
+	With --review-requested or --team-review-requested, the pull requests
+	waiting on your (or the team's) review are listed instead, with how long
+	the review request has been waiting.
 
-	--waiting, -w              Only show pull requests that have no reviews
-	
-	--table, -t                Show the output in a single table, sorted by
-	                           repository
+	If no arguments are given, list just pull requests  and their status for
+	Terraform OSS team members only, grouped by user. With --team, the members
+	of those teams are listed instead, grouped under a heading per team when
+	there's more than one, and "Other" for collaborators and --users.
+
+%s
 
 
 Examples:
@@ -107,12 +200,15 @@ Examples:
 		   provider-aws         [WIP] provider/aws: Add support for Network L[...]      https://github.com/terraform-providers/terraform-provider-aws/pull/1629
 	  [..]
 
+	$ tfteam prs --team=terraform-providers/google --team=terraform-providers/azure
+	  [..]
+
 	$ tfteam prs -f=catsby
 		catsby
 		?  tf-deploy    Fix issue releasing Core                                https://github.com/hashicorp/tf-deploy/pull/7
 
 `
-	return strings.TrimSpace(helpText)
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flags(&prsOptions{}))))
 }
 
 func (c PRsCommand) Synopsis() string {
@@ -120,106 +216,236 @@ func (c PRsCommand) Synopsis() string {
 }
 
 func (c PRsCommand) Run(args []string) int {
-	key := os.Getenv("GITHUB_API_TOKEN")
-	if key == "" {
-		c.UI.Error("Missing API Token!")
+	var opts prsOptions
+	if err := c.process(c.flags(&opts), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
 		return 1
 	}
 
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	if opts.ci != "" && !containsString([]string{"failing", "passing", "pending"}, opts.ci) {
+		c.UI.Error(fmt.Sprintf("--ci must be one of failing, passing or pending, got %q", opts.ci))
+		c.UI.Error(c.Help())
+		return 1
+	}
 
-	// if -c or --collaborators, call orgs/tf-providers/outside_collaborators
-	// and append non-junk users to ml slice above
-	if len(args) > 0 {
-		for _, a := range args {
-			if a == "--collaborators" || a == "-c" {
-				collaborators = true
-			}
-			if a == "--table" || a == "-t" {
-				tableFormat = true
-			}
-			if a == "--waiting" || a == "-w" {
-				filter = StatusWaiting
-			}
-			if a == "--all" || a == "-a" {
-				all = true
-			}
-			if strings.Contains(a, "--users") || strings.Contains(a, "-u") {
-				parts := strings.Split(a, "=")
-				// parts 0 is "--users" or "-u"
-				if len(parts) > 1 {
-					includeUsers = strings.Split(parts[1], ",")
-				} else {
-					log.Printf("no user given")
-				}
-			}
-			if strings.Contains(a, "--filter") || strings.Contains(a, "-f") {
-				parts := strings.Split(a, "=")
-				// parts 0 is "--users" or "-u"
-				if len(parts) > 1 {
-					filterUsers = strings.Split(parts[1], ",")
-				} else {
-					log.Printf("no filter user given")
-				}
+	if opts.conflicts {
+		opts.mergeStatus = true
+	}
+	if opts.owner != "" {
+		opts.codeOwners = true
+	}
+
+	if opts.groupBy == "" {
+		opts.groupBy = "author"
+		if opts.table {
+			opts.groupBy = "repo"
+		}
+	}
+	if !containsString(prGroupBys, opts.groupBy) {
+		c.UI.Error(fmt.Sprintf("--group-by must be one of %s, got %q", strings.Join(prGroupBys, ", "), opts.groupBy))
+		c.UI.Error(c.Help())
+		return 1
+	}
+	if opts.sortBy == "" {
+		opts.sortBy = "created"
+	}
+	if !containsString(prSorts, opts.sortBy) {
+		c.UI.Error(fmt.Sprintf("--sort must be one of %s, got %q", strings.Join(prSorts, ", "), opts.sortBy))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	if opts.excludeWIP && opts.onlyWIP {
+		c.UI.Error("--exclude-wip and --only-wip can't be used together")
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	for _, s := range opts.teams {
+		t, err := parseTeam(s)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("--team: %s", err))
+			c.UI.Error(c.Help())
+			return 1
+		}
+		opts.teamConfigs = append(opts.teamConfigs, t)
+	}
+
+	minSize, maxSize := 0, len(prSizes)-1
+	for _, s := range []struct {
+		flag, value string
+		index       *int
+	}{
+		{"--min-size", opts.minSize, &minSize},
+		{"--max-size", opts.maxSize, &maxSize},
+	} {
+		if s.value == "" {
+			continue
+		}
+		if *s.index = sizeIndex(s.value); *s.index < 0 {
+			c.UI.Error(fmt.Sprintf("%s must be one of XS, S, M, L or XL, got %q", s.flag, s.value))
+			c.UI.Error(c.Help())
+			return 1
+		}
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	filter := StatusAll
+	if opts.waiting {
+		filter = StatusWaiting
+	}
+	if opts.rereview {
+		filter = StatusUpdated
+	}
+
+	q := &searchQuery{}
+	q.add("is", "open")
+	q.add("is", "pr")
+	if opts.reviewRequested || opts.teamReviewRequested != "" {
+		key, value, err := c.reviewRequestedQualifier(ctx, client, &opts)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		q.add(key, value)
+	} else {
+		// without any authors there's nothing to search
+		q.anyOf("author", c.authors(ctx, client, &opts))
+	}
+
+	tfIssues := c.searchPRs(ctx, client, q)
+	if opts.suggest && len(tfIssues) > 0 {
+		// suggest members of the teams we listed, ex. with --team, like assign
+		groups := opts.groups
+		if len(groups) == 0 {
+			// not listed by author, ex. with --review-requested
+			teams := c.Config.Teams
+			if len(opts.teamConfigs) > 0 {
+				teams = opts.teamConfigs
 			}
+			groups = c.teamMembers(ctx, client, teams)
+		}
+		opts.candidates = c.reviewerPool(groups)
+		opts.reviewLoad = c.openReviews(ctx, client, opts.candidates)
+	}
+	errs := c.fetchDetails(ctx, client, tfIssues, &opts)
+
+	out := &prsOutput{
+		table:           opts.table,
+		filter:          filter,
+		mergeStatus:     opts.mergeStatus,
+		maxBehind:       opts.maxBehind,
+		reviewRequested: opts.requestedFor != "",
+		suggest:         opts.suggest,
+		codeOwners:      opts.codeOwners,
+		check:           opts.check,
+		groups:          opts.groups,
+		groupBy:         opts.groupBy,
+		now:             time.Now(),
+	}
+	checked := 0
+	for i, r := range tfIssues {
+		if errs[i] != nil {
+			c.fail(r.HTMLURL, errs[i])
+			continue
+		}
+		checked++
+		// there's better logic here for this kind of sort, using > and the
+		// ordering of the status, but I'm going on like 4 hours of sleep so
+		// ¯\_(ツ)_/¯
+		if filter > 0 && filter != r.StatusCode() {
+			continue
+		}
+		if !r.MatchesCI(opts.ci) {
+			continue
+		}
+		if opts.conflicts && !r.NeedsRebase(opts.maxBehind) {
+			continue
+		}
+		if size := r.sizeIndex(); size < minSize || size > maxSize {
+			continue
 		}
+		if (opts.excludeWIP && r.WIP) || (opts.onlyWIP && !r.WIP) {
+			continue
+		}
+		if opts.owner != "" && !r.IsOwnedBy(opts.owner) {
+			continue
+		}
+		out.PullRequests = append(out.PullRequests, r)
+	}
+	sortPRs(out.PullRequests, opts.groupBy, opts.sortBy, opts.reverse, out.now)
+
+	if err := c.render(out); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
 	}
 
+	return c.exitStatus(checked)
+}
+
+// authors returns the sorted logins of the team members, collaborators and
+// users picked by the flags
+func (m *Meta) authors(ctx context.Context, client *github.Client, opts *prsOptions) []string {
 	ml := make(map[string]string)
 
 	var members []*github.User
 	// refactor, this is boilerplate
-	if !collaborators || all {
-		opt := &github.OrganizationListTeamMembersOptions{Role: "all"}
-		// TODO check pagination
-		teamMembers, _, err := client.Organizations.ListTeamMembers(ctx, tfTeamId, opt)
-		if err != nil {
-			fmt.Println("Error: ", err)
-			os.Exit(1)
+	if !opts.collaborators || opts.all || len(opts.teamConfigs) > 0 {
+		teams := m.Config.Teams
+		if len(opts.teamConfigs) > 0 {
+			teams = opts.teamConfigs
+		}
+		opts.groups = m.teamMembers(ctx, client, teams)
+		for _, g := range opts.groups {
+			for _, member := range g.Members {
+				login := member
+				members = append(members, &github.User{Login: &login})
+			}
 		}
-		members = append(members, teamMembers...)
 	}
 
-	if collaborators || all {
+	if opts.collaborators || opts.all {
 		var collabMembers []*github.User
-		copt := &github.ListOutsideCollaboratorsOptions{}
-		for {
-			outsideCollaborators, resp, err := client.Organizations.ListOutsideCollaborators(ctx, "terraform-providers", copt)
-			if err != nil {
-				log.Printf("Error getting collabs")
-			} else {
+		for _, org := range m.Config.CollaboratorOrgs() {
+			copt := &github.ListOutsideCollaboratorsOptions{}
+			err := paginate(&copt.ListOptions, m.Config.GitHub.PerPage, func() (*github.Response, error) {
+				outsideCollaborators, resp, err := client.Organizations.ListOutsideCollaborators(ctx, org, copt)
 				collabMembers = append(collabMembers, outsideCollaborators...)
+				return resp, err
+			})
+			if err != nil {
+				m.fail(org, fmt.Errorf("error listing outside collaborators: %s", err))
 			}
-			if resp.NextPage == 0 {
-				break
-			}
-			copt.Page = resp.NextPage
 		}
 		members = append(members, collabMembers...)
 	}
 
 	// filter out junk memebers
-	for _, m := range members {
-		if *m.Login != "hashicorp-fossa" && *m.Login != "tf-release-bot" {
-			ml[*m.Login] = *m.Login
+	for _, u := range members {
+		if !m.Config.IsBot(*u.Login) {
+			ml[*u.Login] = *u.Login
 		}
 	}
 
-	for _, u := range includeUsers {
+	for _, u := range opts.includeUsers {
 		ml[u] = u
 	}
 
-	if len(filterUsers) > 0 {
+	if len(opts.filterUsers) > 0 {
 		// only look at these users, skip later blocks
 		newList := make(map[string]string)
-		collaborators = false
-		all = false
-		for _, u := range filterUsers {
+		opts.collaborators = false
+		opts.all = false
+		for _, u := range opts.filterUsers {
 			for _, v := range ml {
 				if strings.Contains(v, u) {
 					newList[v] = v
@@ -229,55 +455,51 @@ func (c PRsCommand) Run(args []string) int {
 		ml = newList
 	}
 
-	// Remove Martin and Bardin FOR NOW b/c they tend to have each other review
-	// PRs regularly
-	delete(ml, "apparentlymart")
-	delete(ml, "jbardin")
-
-	// combine all the members into a single author string so we only hit GitHub
-	// search once
-	authorStr := ""
-	for _, m := range ml {
-		authorStr = fmt.Sprintf("author:%s %s", m, authorStr)
+	// Remove excluded reviewers (Martin and Bardin by default) b/c they tend to
+	// have each other review PRs regularly
+	if !opts.keepExcluded {
+		for _, u := range m.Config.ExcludedReviewers {
+			delete(ml, u)
+		}
 	}
 
-	// search for list by all these authors
-	sopt := &github.SearchOptions{}
+	var authors []string
+	for _, login := range ml {
+		authors = append(authors, login)
+	}
+	sort.Strings(authors)
+	return authors
+}
 
-	var issues []github.Issue
-	for {
-		sresults, resp, err := client.Search.Issues(ctx, fmt.Sprintf("state:open %s type:pr", authorStr), sopt)
-		if err != nil {
-			c.UI.Warn(fmt.Sprintf("Error Searching Issues: %s", err))
-			return 1
-		}
-		issues = append(issues, sresults.Issues...)
-		if resp.NextPage == 0 {
-			break
+// reviewRequestedQualifier returns the search qualifier for
+// --review-requested, which is for the authenticated user, or
+// --team-review-requested. It sets opts.requestedFor to who the requests are
+// for.
+func (c *PRsCommand) reviewRequestedQualifier(ctx context.Context, client *github.Client, opts *prsOptions) (string, string, error) {
+	if opts.teamReviewRequested != "" {
+		if !validRepoName(opts.teamReviewRequested) {
+			return "", "", fmt.Errorf("--team-review-requested must be in org/team form, got %q", opts.teamReviewRequested)
 		}
-		sopt.Page = resp.NextPage
+		opts.requestedFor = opts.teamReviewRequested
+		return "team-review-requested", opts.teamReviewRequested, nil
 	}
 
+	user, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return "", "", fmt.Errorf("error getting the authenticated user: %s", err)
+	}
+	opts.requestedFor = user.GetLogin()
+	return "review-requested", user.GetLogin(), nil
+}
+
+// searchPRs returns the open PRs matching q in the repositories we care
+// about
+func (m *Meta) searchPRs(ctx context.Context, client *github.Client, q *searchQuery) []*TFPr {
+	issues, _ := m.search(ctx, client, q)
+
 	// Filter out PRs that aren't involving Terraform
 	tfIssues := []*TFPr{}
 	for _, i := range issues {
-		// sneak some other related projects in. This cascading if statements look
-		// hilarious
-		if !strings.Contains(*i.HTMLURL, "terraform") {
-			if !strings.Contains(*i.HTMLURL, "tfteam") {
-				if !strings.Contains(*i.HTMLURL, "engservices-teamcity") {
-					if !strings.Contains(*i.HTMLURL, "tf-deploy") {
-						continue
-					}
-				}
-			}
-		}
-
-		// filter out this test repo
-		if strings.Contains(*i.HTMLURL, "hashibot-test/terraform-provider-archive") {
-			continue
-		}
-
 		var repo string
 		var owner string
 		u, err := url.Parse(*i.HTMLURL)
@@ -288,6 +510,13 @@ func (c PRsCommand) Run(args []string) int {
 			owner = parts[1]
 			repo = parts[2]
 		}
+
+		// only Terraform related repositories, see repo_patterns and
+		// ignored_repos in the config
+		if !m.Config.MatchesRepo(owner + "/" + repo) {
+			continue
+		}
+
 		tfpr := TFPr{
 			User:      i.User,
 			HTMLURL:   *i.HTMLURL,
@@ -298,130 +527,283 @@ func (c PRsCommand) Run(args []string) int {
 			Owner:     owner,
 			Repo:      repo,
 		}
+		for _, l := range i.Labels {
+			tfpr.Labels = append(tfpr.Labels, l.GetName())
+		}
 		tfIssues = append(tfIssues, &tfpr)
 	}
+	return tfIssues
+}
 
-	// 5 "workers" to do things concurrently
-	count := 5
-	wgPrs.Add(count)
-
-	// queue of TFPRs to query on the review status
-	tfprChan := make(chan *TFPr, len(tfIssues))
+// fetchDetails queries the reviews, CI status and whatever else the flags ask
+// for of each PR concurrently. It returns the error of each PR, in order.
+func (m *Meta) fetchDetails(ctx context.Context, client *github.Client, prs []*TFPr, opts *prsOptions) []error {
+	perPage := m.Config.GitHub.PerPage
+	codeowners := &codeownersCache{}
+	return runPool(ctx, m.concurrency, len(prs), func(ctx context.Context, i int) error {
+		pr := prs[i]
+		if err := getApprovalStatus(ctx, client, perPage, pr); err != nil {
+			return fmt.Errorf("error listing reviews: %s", err)
+		}
+		if err := getPullRequest(ctx, client, pr); err != nil {
+			return fmt.Errorf("error getting pull request: %s", err)
+		}
+		pr.setWIP(m.Config.WIPLabels)
+		if err := getCIStatus(ctx, client, perPage, pr); err != nil {
+			return fmt.Errorf("error getting CI status: %s", err)
+		}
+		if opts.mergeStatus {
+			// a failed compare only leaves the Merge column unknown, ex.
+			// for PRs from deleted forks
+			if err := getBehindBy(ctx, client, pr); err != nil {
+				pr.BehindUnknown = true
+			}
+		}
+		if opts.suggest || opts.codeOwners || opts.check {
+			files, err := listPRFiles(ctx, client, perPage, pr.Owner, pr.Repo, pr.Number)
+			if err != nil {
+				return fmt.Errorf("error listing files: %s", err)
+			}
+			pr.Files = fileNames(files)
+			if opts.check {
+				pr.Checks = checkProviderFiles(files)
+			}
+		}
+		if opts.codeOwners {
+			if err := getCodeOwners(ctx, client, pr, codeowners); err != nil {
+				return fmt.Errorf("error reading CODEOWNERS: %s", err)
+			}
+		}
+		if opts.suggest {
+			if err := getSuggestedReviewers(ctx, client, perPage, pr, opts.candidates, opts.reviewLoad); err != nil {
+				return fmt.Errorf("error suggesting reviewers: %s", err)
+			}
+		}
+		if opts.requestedFor != "" {
+			if err := getReviewRequestedAt(ctx, client, perPage, pr, opts.requestedFor); err != nil {
+				return fmt.Errorf("error listing events: %s", err)
+			}
+		}
+		return nil
+	})
+}
 
-	// recieve results from PR review queries
-	resultsChan := make(chan *TFPr, len(tfIssues))
+// prsOutput is the result of `tfteam prs`
+type prsOutput struct {
+	PullRequests []*TFPr `json:"pull_requests"`
+
+	table       bool
+	filter      PRReviewStatus
+	mergeStatus bool
+	maxBehind   int
+
+	// reviewRequested adds the Waiting column, suggest the Suggested one,
+	// codeOwners the Owners one and check the Checks one
+	reviewRequested bool
+	suggest         bool
+	codeOwners      bool
+	check           bool
+
+	// groups are the teams the by-user output is grouped under
+	groups []teamGroup
+
+	// groupBy is how PullRequests, sorted by sortPRs, are grouped, and now
+	// the time their ages are from
+	groupBy string
+	now     time.Time
+}
 
-	// Setup go() workers
-	for gr := 1; gr <= count; gr++ {
-		go getApprovalStatus(tfprChan, resultsChan)
-	}
+func (o *prsOutput) Header() []string {
+	return []string{"status", "created_at", "owner", "repo", "author", "title", "url", "reviewers", "ci", "merge", "size", "additions", "deletions", "changed_files", "commits", "review_requested_at", "wip", "suggested_reviewers", "code_owners", "checks"}
+}
 
-	// Feed PRs into the queue
-	for _, i := range tfIssues {
-		tfprChan <- i
+func (o *prsOutput) Rows() [][]string {
+	var rows [][]string
+	for _, pr := range o.PullRequests {
+		rows = append(rows, []string{
+			pr.StatusCode().String(),
+			formatTime(pr.CreatedAt),
+			pr.Owner,
+			pr.Repo,
+			*pr.User.Login,
+			pr.Title,
+			pr.HTMLURL,
+			pr.ReviewersString(),
+			pr.CIString(),
+			pr.MergeString(o.maxBehind),
+			pr.Size(),
+			strconv.Itoa(pr.Additions),
+			strconv.Itoa(pr.Deletions),
+			strconv.Itoa(pr.ChangedFiles),
+			strconv.Itoa(pr.Commits),
+			formatTime(pr.RequestedAt),
+			strconv.FormatBool(pr.WIP),
+			strings.Join(pr.SuggestedReviewers, " "),
+			strings.Join(pr.CodeOwners, " "),
+			completenessString(pr.Checks),
+		})
 	}
+	return rows
+}
 
-	close(tfprChan)
-	wgPrs.Wait()
-	close(resultsChan)
-
-	if tableFormat {
-		// convert results into a map of users/user prs for sorting
-		rl := make(map[string][]*TFPr)
-		for r := range resultsChan {
-			rl[r.Repo] = append(rl[r.Repo], r)
-		}
-
-		var keys []string
-		for k, _ := range rl {
-			keys = append(keys, k)
-		}
-
-		sort.Strings(keys)
-
+func (o *prsOutput) Table(out io.Writer) {
+	if o.table {
 		w := new(tabwriter.Writer)
 		// w.Init(os.Stdout, 5, 2, 1, '\t', 0)
-		w.Init(os.Stdout, 0, 8, 0, '\t', 0)
+		w.Init(out, 0, 8, 1, '\t', 0)
 		// change table format to remove status column if we're just looking at
 		// waiting reviews
-		tableFormat := "Status\tCreated At\tRepo\tAuthor\tTitle\tLink"
-		if filter == StatusWaiting {
-			tableFormat = "Repo\tAuthor\tTitle\tLink"
+		tableFormat := "Status\tCreated At\tRepo\tAuthor\tTitle\tLink\tSize\tReviewers\tCI"
+		if o.filter == StatusWaiting {
+			tableFormat = "Repo\tAuthor\tTitle\tLink\tSize\tCI"
+		}
+		if o.mergeStatus {
+			tableFormat += "\tMerge"
+		}
+		if o.reviewRequested {
+			tableFormat += "\tWaiting"
+		}
+		if o.suggest {
+			tableFormat += "\tSuggested"
+		}
+		if o.codeOwners {
+			tableFormat += "\tOwners"
+		}
+		if o.check {
+			tableFormat += "\tChecks"
 		}
 		fmt.Fprintln(w, tableFormat)
-		for _, k := range keys {
-			// sort by created at date
-			sort.Sort(TFPRGroup(rl[k]))
-			for _, pr := range rl[k] {
-				// there's better logic here for this kind of sort, using > and the
-				// ordering of the status, but I'm going on like 4 hours of sleep so
-				// ¯\_(ツ)_/¯
-				if filter > 0 {
-					if filter != pr.StatusCode() {
-						continue
-					}
-				}
-				if filter == StatusWaiting {
-					fmt.Fprintln(w, fmt.Sprintf("%s\t%s\t%s\t%s", strings.TrimPrefix(k, "terraform-"), *pr.User.Login, pr.TitleTruncated(), pr.HTMLURL))
-				} else {
-					fmt.Fprintln(w, fmt.Sprintf("%s\t%s\t%s\t%s\t%s", pr.IsApprovedString(), strings.TrimPrefix(k, "terraform-"), *pr.User.Login, pr.TitleTruncated(), pr.HTMLURL))
-				}
+		// PullRequests are already in group order
+		for _, pr := range o.PullRequests {
+			repo := strings.TrimPrefix(pr.Repo, "terraform-")
+			var row string
+			if o.filter == StatusWaiting {
+				row = fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", repo, *pr.User.Login, pr.TitleTruncated(), pr.HTMLURL, pr.SizeString(), pr.CIString())
+			} else {
+				row = fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", pr.IsApprovedString(), pr.CreatedAt.Format("Mon 01/02/2006"), repo, *pr.User.Login, pr.TitleTruncated(), pr.HTMLURL, pr.SizeString(), pr.ReviewersString(), pr.CIString())
+			}
+			if o.mergeStatus {
+				row += "\t" + pr.MergeString(o.maxBehind)
 			}
+			if o.reviewRequested {
+				row += "\t" + pr.WaitingString()
+			}
+			if o.suggest {
+				row += "\t" + strings.Join(pr.SuggestedReviewers, ", ")
+			}
+			if o.codeOwners {
+				row += "\t" + strings.Join(pr.CodeOwners, ", ")
+			}
+			if o.check {
+				row += "\t" + completenessString(pr.Checks)
+			}
+			fmt.Fprintln(w, row)
 		}
 		w.Flush()
-	} else {
-		// User format
-		rl := make(map[string][]*TFPr)
-		for r := range resultsChan {
-			rl[*r.User.Login] = append(rl[*r.User.Login], r)
-		}
-		// sort Team members by Alpha order sorry vancluever
-		var keys []string
-		for k, _ := range rl {
-			keys = append(keys, k)
+		return
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 0, '\t', 0)
+	defer w.Flush()
+
+	if o.groupBy != "author" {
+		for _, g := range groupPRs(o.PullRequests, o.groupBy, o.now) {
+			if g.Name != "" {
+				fmt.Fprintln(w, g.Name)
+			}
+			for _, pr := range g.PullRequests {
+				o.writeLine(w, pr, true)
+			}
+			fmt.Fprintln(w)
 		}
+		return
+	}
+
+	// User format
+	rl := make(map[string][]*TFPr)
+	for _, r := range o.PullRequests {
+		rl[*r.User.Login] = append(rl[*r.User.Login], r)
+	}
+	// sort Team members by Alpha order sorry vancluever
+	var keys []string
+	for k, _ := range rl {
+		keys = append(keys, k)
+	}
 
-		sort.Strings(keys)
+	sort.Strings(keys)
 
-		w := new(tabwriter.Writer)
-		w.Init(os.Stdout, 0, 8, 0, '\t', 0)
-		for _, k := range keys {
-			if len(rl[k]) > 0 {
-				// if we're filtering out to just show waiting ones, make sure we have
-				// some
-				var waitingCount int
-				// sort by created at date
-				sort.Sort(TFPRGroup(rl[k]))
-				for _, pr := range rl[k] {
-					if filter != pr.StatusCode() {
-						continue
-					}
-					waitingCount++
-				}
+	sections := o.teamSections(keys)
+	for _, s := range sections {
+		if len(sections) > 1 {
+			fmt.Fprintf(w, "%s\n%s\n\n", s.Name, strings.Repeat("=", len(s.Name)))
+		}
+		for _, k := range s.Members {
+			fmt.Fprintln(w, k)
+			for _, pr := range rl[k] {
+				o.writeLine(w, pr, false)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
 
-				if filter == StatusWaiting && waitingCount == 0 {
-					continue
-				}
-				fmt.Fprintln(w, k)
-
-				for _, pr := range rl[k] {
-					// there's better logic here for this kind of sort, using > and the
-					// ordering of the status, but I'm going on like 4 hours of sleep so
-					// ¯\_(ツ)_/¯
-					if filter > 0 {
-						if filter != pr.StatusCode() {
-							continue
-						}
-					}
-					fmt.Fprintln(w, fmt.Sprintf("%s  %s  %s  %s  %s", pr.IsApprovedString(), pr.CreatedAt.Format("Mon 01/02/2006"), strings.TrimPrefix(pr.Repo, "terraform-provider-"), pr.TitleTruncated(), pr.HTMLURL))
-				}
-				fmt.Fprintln(w)
+// writeLine writes pr as a line of the grouped output, with its author unless
+// the group is already the author
+func (o *prsOutput) writeLine(w io.Writer, pr *TFPr, author bool) {
+	ci := pr.CIString()
+	if o.mergeStatus {
+		ci += "  " + pr.MergeString(o.maxBehind)
+	}
+	if o.reviewRequested {
+		ci += "  waiting " + pr.WaitingString()
+	}
+	if o.suggest && len(pr.SuggestedReviewers) > 0 {
+		ci += "  suggested " + strings.Join(pr.SuggestedReviewers, ", ")
+	}
+	if o.codeOwners && len(pr.CodeOwners) > 0 {
+		ci += "  owners " + strings.Join(pr.CodeOwners, ", ")
+	}
+	if o.check && len(pr.Checks) > 0 {
+		ci += "  checks " + completenessString(pr.Checks)
+	}
+	repo := strings.TrimPrefix(pr.Repo, "terraform-provider-")
+	if author {
+		repo += "  " + pr.GetLogin()
+	}
+	line := fmt.Sprintf("%s  %s  %s  %s  %s  %s  %s", pr.IsApprovedString(), pr.CreatedAt.Format("Mon 01/02/2006"), repo, pr.TitleTruncated(), pr.HTMLURL, ci, pr.ReviewersString())
+	fmt.Fprintln(w, strings.TrimRight(line, " "))
+}
+
+// teamSections splits the sorted authors by team, for the by-user headings.
+// Someone in more than one team is listed under the first, and authors in
+// none, ex. collaborators, under "Other". Teams without PRs are left out.
+func (o *prsOutput) teamSections(authors []string) []teamGroup {
+	placed := make(map[string]bool)
+	var sections []teamGroup
+	for _, g := range o.groups {
+		section := teamGroup{Name: g.Name}
+		for _, a := range authors {
+			if !placed[a] && containsString(g.Members, a) {
+				placed[a] = true
+				section.Members = append(section.Members, a)
 			}
-			w.Flush()
+		}
+		if len(section.Members) > 0 {
+			sections = append(sections, section)
 		}
 	}
 
-	return 0
+	other := teamGroup{Name: "Other"}
+	for _, a := range authors {
+		if !placed[a] {
+			other.Members = append(other.Members, a)
+		}
+	}
+	if len(other.Members) > 0 {
+		sections = append(sections, other)
+	}
+	return sections
 }
 
 type ByReviewDate []*github.PullRequestReview
@@ -433,38 +815,169 @@ func (a ByReviewDate) Less(i, j int) bool {
 	return a[j].SubmittedAt.Before(*a[i].SubmittedAt)
 }
 
-func getApprovalStatus(prsChan <-chan *TFPr, rChan chan<- *TFPr) {
-	defer wgPrs.Done()
-	// should pass in and reususe context I think?
-	key := os.Getenv("GITHUB_API_TOKEN")
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+// getApprovalStatus sets pr.Reviewers and pr.State from the PR's reviews
+func getApprovalStatus(ctx context.Context, client *github.Client, perPage int, pr *TFPr) error {
+	reviews, err := listReviews(ctx, client, perPage, pr.Owner, pr.Repo, pr.Number)
+	if err != nil {
+		return err
+	}
+
+	pr.setReviews(reviews)
+	return nil
+}
+
+// listReviews lists every review of a PR, oldest first
+func listReviews(ctx context.Context, client *github.Client, perPage int, owner, repo string, number int) ([]*github.PullRequestReview, error) {
+	var reviews []*github.PullRequestReview
+	ropt := &github.ListOptions{}
+	err := paginate(ropt, perPage, func() (*github.Response, error) {
+		part, resp, err := client.PullRequests.ListReviews(ctx, owner, repo, number, ropt)
+		reviews = append(reviews, part...)
+		return resp, err
+	})
+	return reviews, err
+}
+
+// draftPreview is the media type GitHub returns the draft flag of PRs with
+const draftPreview = "application/vnd.github.shadow-cat-preview+json"
+
+// draftPullRequest is a pull request with the draft flag and requested
+// reviewers the vendored go-github doesn't decode
+type draftPullRequest struct {
+	github.PullRequest
+	Draft              bool           `json:"draft"`
+	RequestedReviewers []*github.User `json:"requested_reviewers"`
+	RequestedTeams     []*github.Team `json:"requested_teams"`
+}
 
-	client := github.NewClient(tc)
+// getPullRequest fetches the PR itself, for the details search results don't
+// have
+func getPullRequest(ctx context.Context, client *github.Client, pr *TFPr) error {
+	u := fmt.Sprintf("repos/%s/%s/pulls/%d", pr.Owner, pr.Repo, pr.Number)
+	req, err := client.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", draftPreview)
+	p := new(draftPullRequest)
+	if _, err := client.Do(ctx, req, p); err != nil {
+		return err
+	}
+	pr.Draft = p.Draft
+	pr.RequestedReviewers = nil
+	for _, u := range p.RequestedReviewers {
+		pr.RequestedReviewers = append(pr.RequestedReviewers, u.GetLogin())
+	}
+	for _, t := range p.RequestedTeams {
+		pr.RequestedReviewers = append(pr.RequestedReviewers, pr.Owner+"/"+t.GetSlug())
+	}
+	pr.HeadSHA = p.GetHead().GetSHA()
+	pr.BaseRef = p.GetBase().GetRef()
+	pr.Mergeable = p.Mergeable
+	pr.MergeableState = p.GetMergeableState()
+	pr.Additions = p.GetAdditions()
+	pr.Deletions = p.GetDeletions()
+	pr.ChangedFiles = p.GetChangedFiles()
+	pr.Commits = p.GetCommits()
+	return nil
+}
 
-	for pr := range prsChan {
-		reviews, _, err := client.PullRequests.ListReviews(ctx, pr.Owner, pr.Repo, pr.Number, nil)
+// getCIStatus sets pr.CI and pr.FailingContexts from the combined status of
+// the head commit
+func getCIStatus(ctx context.Context, client *github.Client, perPage int, pr *TFPr) error {
+	var state string
+	var statuses []github.RepoStatus
+	opt := &github.ListOptions{}
+	err := paginate(opt, perPage, func() (*github.Response, error) {
+		combined, resp, err := client.Repositories.GetCombinedStatus(ctx, pr.Owner, pr.Repo, pr.HeadSHA, opt)
 		if err != nil {
-			log.Printf("error getting review:%s", err)
-			continue
+			return resp, err
 		}
+		state = combined.GetState()
+		statuses = append(statuses, combined.Statuses...)
+		return resp, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// GitHub says "pending" when there are no statuses at all
+	pr.CI = ""
+	pr.FailingContexts = nil
+	if len(statuses) == 0 {
+		return nil
+	}
+	pr.CI = state
+	for _, s := range statuses {
+		if s.GetState() == "failure" || s.GetState() == "error" {
+			pr.FailingContexts = append(pr.FailingContexts, s.GetContext())
+		}
+	}
+	sort.Strings(pr.FailingContexts)
+	return nil
+}
+
+// getBehindBy sets how many commits the PR's head is behind its base branch.
+// The head is compared by SHA, which the base repository has even when the
+// fork it came from is gone.
+func getBehindBy(ctx context.Context, client *github.Client, pr *TFPr) error {
+	comparison, _, err := client.Repositories.CompareCommits(ctx, pr.Owner, pr.Repo, pr.BaseRef, pr.HeadSHA)
+	if err != nil {
+		return err
+	}
+	pr.BehindBy = comparison.GetBehindBy()
+	return nil
+}
 
-		// questionable logic here; pop the last review and use that as the status.
-		// A better strategy would probably be collect a map of reviews by reviewer.
-		// If any of them are "requested changes", then the status is
-		// CHANGES_REQUESTED, even if another reviewer approved. The only way to
-		// make approved would be if the reviewer(s) that gave CHANGES_REQUESTED,
-		// also but later, gave an APPROVED. STill not 100% but probably better than
-		// below
-		sort.Sort(ByReviewDate(reviews))
-		if len(reviews) > 0 {
-			r := reviews[0]
-			pr.State = *r.State
+// issueEvent is an issue event with the review request fields the vendored
+// go-github doesn't decode
+type issueEvent struct {
+	Event             string       `json:"event"`
+	Actor             *github.User `json:"actor"`
+	CreatedAt         *time.Time   `json:"created_at"`
+	RequestedReviewer *github.User `json:"requested_reviewer"`
+	RequestedTeam     *github.Team `json:"requested_team"`
+}
+
+// listIssueEvents lists every event of an issue or PR, oldest first
+func listIssueEvents(ctx context.Context, client *github.Client, perPage int, owner, repo string, number int) ([]*issueEvent, error) {
+	var events []*issueEvent
+	opt := &github.ListOptions{}
+	err := paginate(opt, perPage, func() (*github.Response, error) {
+		u := fmt.Sprintf("repos/%s/%s/issues/%d/events?page=%d&per_page=%d", owner, repo, number, opt.Page, opt.PerPage)
+		req, err := client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
 		}
+		var part []*issueEvent
+		resp, err := client.Do(ctx, req, &part)
+		events = append(events, part...)
+		return resp, err
+	})
+	return events, err
+}
+
+// getReviewRequestedAt sets pr.RequestedAt to when a review was last requested
+// from requestedFor, a login or org/team. PRs without a matching event, ex.
+// ones opened with reviewers already requested, use the PR's creation time.
+func getReviewRequestedAt(ctx context.Context, client *github.Client, perPage int, pr *TFPr, requestedFor string) error {
+	events, err := listIssueEvents(ctx, client, perPage, pr.Owner, pr.Repo, pr.Number)
+	if err != nil {
+		return err
+	}
 
-		rChan <- pr
+	pr.RequestedAt = pr.CreatedAt
+	for _, e := range events {
+		if e.Event != "review_requested" || e.CreatedAt == nil {
+			continue
+		}
+		match := e.RequestedReviewer.GetLogin() == requestedFor
+		if e.RequestedTeam != nil {
+			match = pr.Owner+"/"+e.RequestedTeam.GetSlug() == requestedFor
+		}
+		if match {
+			pr.RequestedAt = e.CreatedAt
+		}
 	}
+	return nil
 }
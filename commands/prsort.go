@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"sort"
+	"time"
+)
+
+// prGroupBys and prSorts are the values of `tfteam prs --group-by` and
+// --sort
+var (
+	prGroupBys = []string{"author", "repo", "status", "age", "none"}
+	prSorts    = []string{"created", "updated", "repo", "status", "size"}
+)
+
+// ageBuckets group PRs by how long ago they were opened, oldest first so the
+// stale ones come first
+var ageBuckets = []struct {
+	Name  string
+	Since time.Duration
+}{
+	{"Older than 30 days", -1},
+	{"This month", 30 * 24 * time.Hour},
+	{"This week", 7 * 24 * time.Hour},
+	{"Today", 24 * time.Hour},
+}
+
+// ageBucket returns the position in ageBuckets of a PR opened at created
+func ageBucket(created *time.Time, now time.Time) int {
+	if created == nil {
+		return 0
+	}
+	age := now.Sub(*created)
+	bucket := 0
+	for i, b := range ageBuckets {
+		if b.Since < 0 || age < b.Since {
+			bucket = i
+		}
+	}
+	return bucket
+}
+
+// statusTitles are the headings of --group-by=status
+var statusTitles = map[PRReviewStatus]string{
+	StatusWaiting:  "No review",
+	StatusComments: "Reviewed, with comments",
+	StatusChanges:  "Changes requested",
+	StatusUpdated:  "Needs re-review",
+	StatusApproved: "Approved",
+}
+
+// prGroup is a heading of the prs output, and its PRs
+type prGroup struct {
+	Name         string
+	PullRequests []*TFPr
+}
+
+// groupOf returns the name of the group pr is in for groupBy, and its rank
+// among the groups. Groups are ordered by rank, then name.
+func groupOf(pr *TFPr, groupBy string, now time.Time) (int, string) {
+	switch groupBy {
+	case "author":
+		return 0, pr.GetLogin()
+	case "repo":
+		return 0, pr.Repo
+	case "status":
+		return int(pr.StatusCode()), statusTitles[pr.StatusCode()]
+	case "age":
+		b := ageBucket(pr.CreatedAt, now)
+		return b, ageBuckets[b].Name
+	}
+	return 0, ""
+}
+
+// sortPRs orders prs by group, then by sortBy within each group. reverse only
+// reverses the order within the groups. Ties are broken by creation time.
+func sortPRs(prs []*TFPr, groupBy, sortBy string, reverse bool, now time.Time) {
+	sort.SliceStable(prs, func(i, j int) bool {
+		a, b := prs[i], prs[j]
+		ar, an := groupOf(a, groupBy, now)
+		br, bn := groupOf(b, groupBy, now)
+		if ar != br {
+			return ar < br
+		}
+		if an != bn {
+			return an < bn
+		}
+		if reverse {
+			a, b = b, a
+		}
+		switch sortBy {
+		case "updated":
+			if !timeEqual(a.UpdatedAt, b.UpdatedAt) {
+				return timeBefore(a.UpdatedAt, b.UpdatedAt)
+			}
+		case "repo":
+			if ak, bk := a.Owner+"/"+a.Repo, b.Owner+"/"+b.Repo; ak != bk {
+				return ak < bk
+			}
+		case "status":
+			if a.StatusCode() != b.StatusCode() {
+				return a.StatusCode() < b.StatusCode()
+			}
+		case "size":
+			if al, bl := a.Additions+a.Deletions, b.Additions+b.Deletions; al != bl {
+				return al < bl
+			}
+		}
+		return timeBefore(a.CreatedAt, b.CreatedAt)
+	})
+}
+
+// groupPRs splits prs, sorted by sortPRs, into their groups
+func groupPRs(prs []*TFPr, groupBy string, now time.Time) []prGroup {
+	var groups []prGroup
+	for _, pr := range prs {
+		_, name := groupOf(pr, groupBy, now)
+		if len(groups) == 0 || groups[len(groups)-1].Name != name {
+			groups = append(groups, prGroup{Name: name})
+		}
+		g := &groups[len(groups)-1]
+		g.PullRequests = append(g.PullRequests, pr)
+	}
+	return groups
+}
+
+// timeBefore orders times, with nil times last
+func timeBefore(a, b *time.Time) bool {
+	switch {
+	case a == nil:
+		return false
+	case b == nil:
+		return true
+	}
+	return a.Before(*b)
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
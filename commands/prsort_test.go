@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestAgeBucket(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	cases := []struct {
+		Name   string
+		Age    time.Duration
+		Bucket string
+	}{
+		{"just opened", 0, "Today"},
+		{"in the future", -time.Hour, "Today"},
+		{"just under a day", day - time.Nanosecond, "Today"},
+		{"a day", day, "This week"},
+		{"just under a week", 7*day - time.Nanosecond, "This week"},
+		{"a week", 7 * day, "This month"},
+		{"just under 30 days", 30*day - time.Nanosecond, "This month"},
+		{"30 days", 30 * day, "Older than 30 days"},
+		{"a year", 365 * day, "Older than 30 days"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			created := now.Add(-tc.Age)
+			actual := ageBuckets[ageBucket(&created, now)].Name
+			if actual != tc.Bucket {
+				t.Fatalf("expected %q, got %q", tc.Bucket, actual)
+			}
+		})
+	}
+
+	if b := ageBucket(nil, now); b != 0 {
+		t.Fatalf("expected a PR without a creation time in bucket 0, got %d", b)
+	}
+}
+
+func TestSortPRs(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) *time.Time {
+		at := now.Add(-time.Duration(d) * 24 * time.Hour)
+		return &at
+	}
+	pr := func(number int, login, repo string, created, updated, size int) *TFPr {
+		return &TFPr{
+			User:      &github.User{Login: github.String(login)},
+			Number:    number,
+			Owner:     "hashicorp",
+			Repo:      repo,
+			CreatedAt: daysAgo(created),
+			UpdatedAt: daysAgo(updated),
+			Additions: size,
+		}
+	}
+	// listed in no particular order
+	prs := []*TFPr{
+		pr(1, "catsby", "terraform", 3, 1, 10),
+		pr(2, "radeksimko", "terraform-provider-aws", 10, 0, 500),
+		pr(3, "catsby", "terraform-provider-aws", 40, 2, 10),
+		pr(4, "radeksimko", "terraform", 0, 0, 50),
+	}
+
+	cases := []struct {
+		Name    string
+		GroupBy string
+		Sort    string
+		Reverse bool
+		Numbers []int
+	}{
+		{"created", "none", "created", false, []int{3, 2, 1, 4}},
+		{"created, reversed", "none", "created", true, []int{4, 1, 2, 3}},
+		{"updated", "none", "updated", false, []int{3, 1, 2, 4}},
+		{"updated, reversed", "none", "updated", true, []int{4, 2, 1, 3}},
+		{"size, ties by created", "none", "size", false, []int{3, 1, 4, 2}},
+		{"size, reversed", "none", "size", true, []int{2, 4, 1, 3}},
+		{"repo", "none", "repo", false, []int{1, 4, 3, 2}},
+		{"repo, reversed", "none", "repo", true, []int{2, 3, 4, 1}},
+		{"by author", "author", "created", false, []int{3, 1, 2, 4}},
+		{"by author, reversed within groups", "author", "created", true, []int{1, 3, 4, 2}},
+		{"by age, reversed within groups", "age", "size", true, []int{3, 2, 1, 4}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			sorted := append([]*TFPr(nil), prs...)
+			sortPRs(sorted, tc.GroupBy, tc.Sort, tc.Reverse, now)
+
+			var numbers []int
+			for _, pr := range sorted {
+				numbers = append(numbers, pr.Number)
+			}
+			if !reflect.DeepEqual(numbers, tc.Numbers) {
+				t.Fatalf("expected %v, got %v", tc.Numbers, numbers)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const (
+	// maxRetries is how many times a request is retried after a rate limit or
+	// server error before the response is handed back to the caller
+	maxRetries = 5
+
+	// maxRateLimitWait caps how long we sleep waiting for a rate limit to
+	// reset. The core limit resets hourly, and nobody wants tfteam to sit
+	// there for that long.
+	maxRateLimitWait = 15 * time.Minute
+
+	// defaultAbuseWait is used when an abuse rate limit error has no
+	// Retry-After header
+	defaultAbuseWait = time.Minute
+)
+
+// retryTransport retries requests that hit GitHub's rate limits, sleeping
+// until the limit resets or for the Retry-After GitHub asks for, and retries
+// idempotent requests that fail with a server error. It records the remaining
+// quota of each resource in Budget.
+type retryTransport struct {
+	Transport http.RoundTripper
+	Budget    *RateBudget
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("can't retry %s %s, request body can't be rewound", req.Method, req.URL)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = cloneRequest(req)
+			req.Body = body
+		}
+
+		resp, err := t.Transport.RoundTrip(req)
+		if err != nil {
+			if !idempotent(req) || attempt >= maxRetries || req.Context().Err() != nil {
+				return nil, err
+			}
+			wait := backoff(attempt)
+			log.Printf("[WARN] %s %s failed, retrying in %s: %s", req.Method, req.URL.Path, wait, err)
+			if err := sleepContext(req.Context(), wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if t.Budget != nil {
+			t.Budget.record(resp)
+		}
+
+		wait, retry := retryAfter(req, resp, attempt)
+		if !retry || attempt >= maxRetries {
+			return resp, nil
+		}
+
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		log.Printf("[WARN] %s %s: %s, retrying in %s", req.Method, req.URL.Path, resp.Status, wait.Round(time.Second))
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter decides if resp should be retried, and how long to wait first
+func retryAfter(req *http.Request, resp *http.Response, attempt int) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		// let go-github classify the error, on a copy of the body so the
+		// caller can still read it
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return 0, false
+		}
+		check := *resp
+		check.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		switch e := github.CheckResponse(&check).(type) {
+		case *github.RateLimitError:
+			wait := time.Until(e.Rate.Reset.Time) + time.Second + jitter(time.Second)
+			if wait < 0 {
+				wait = time.Second
+			}
+			return wait, wait <= maxRateLimitWait
+		case *github.AbuseRateLimitError:
+			wait := defaultAbuseWait
+			if e.RetryAfter != nil {
+				wait = *e.RetryAfter
+			}
+			return wait + jitter(wait/4), true
+		}
+
+		// newer secondary rate limits aren't classified by go-github, but
+		// still come with Retry-After
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			if secs, err := strconv.Atoi(v); err == nil {
+				wait := time.Duration(secs) * time.Second
+				return wait + jitter(wait/4), true
+			}
+		}
+	case resp.StatusCode >= 500 && idempotent(req):
+		return backoff(attempt), true
+	}
+	return 0, false
+}
+
+func idempotent(req *http.Request) bool {
+	return req.Method == http.MethodGet || req.Method == http.MethodHead
+}
+
+// backoff is an exponential backoff starting at one second, with jitter so
+// concurrent workers don't retry in lock step
+func backoff(attempt int) time.Duration {
+	d := time.Second << uint(attempt)
+	return d + jitter(d/2)
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// sleepContext waits for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RateBudget tracks the last known rate limit of the core and search API
+// resources, from the headers of each response
+type RateBudget struct {
+	mu    sync.Mutex
+	rates map[string]github.Rate
+}
+
+func (b *RateBudget) record(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	rate := github.Rate{Limit: limit, Remaining: remaining}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		rate.Reset = github.Timestamp{Time: time.Unix(reset, 0)}
+	}
+
+	resource := "core"
+	if resp.Request != nil && strings.Contains(resp.Request.URL.Path, "/search/") {
+		resource = "search"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rates == nil {
+		b.rates = make(map[string]github.Rate)
+	}
+	b.rates[resource] = rate
+}
+
+// String summarizes the remaining quota, ex.
+//
+//	Rate limit: core 4890/5000 (resets 15:04), search 28/30 (resets 14:32)
+func (b *RateBudget) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var parts []string
+	for _, resource := range []string{"core", "search"} {
+		rate, ok := b.rates[resource]
+		if !ok {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %d/%d (resets %s)", resource, rate.Remaining, rate.Limit, rate.Reset.Local().Format("15:04")))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Rate limit: " + strings.Join(parts, ", ")
+}
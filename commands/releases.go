@@ -2,27 +2,47 @@ package commands
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"log"
-	"os"
+	"io"
 	"sort"
 	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	"golang.org/x/oauth2"
-
 	"github.com/google/go-github/github"
-	"github.com/mitchellh/cli"
 )
 
 type ReleasesCommand struct {
-	UI cli.Ui
+	Meta
 }
 
 func (c ReleasesCommand) Help() string {
-	return "Help - todo"
+	helpText := `
+Usage: tfteam releases [options]
+
+	List providers from the configured orgs, and Terraform core, by the date of
+	their latest release tag. The most recently released are listed first.
+
+%s
+
+Examples:
+
+  $ tfteam releases -b     // Sort providers by name
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flags(&releasesOptions{}))))
+}
+
+// releasesOptions are the flags accepted by `tfteam releases`
+type releasesOptions struct {
+	byName bool
+}
+
+func (c *ReleasesCommand) flags(o *releasesOptions) *flag.FlagSet {
+	f := c.flagSet("releases")
+	boolVar(f, &o.byName, "by-name", "b", "Sort providers by repository name instead of release date")
+	return f
 }
 
 func (c ReleasesCommand) Synopsis() string {
@@ -33,10 +53,10 @@ func (c ReleasesCommand) Synopsis() string {
 // actually create "Releases" in the GitHub API, we just have tags (except maybe
 // hashicorp/terraform?), so it got converted to release tags ¯\_(ツ)_/¯
 type RepoReleaseTag struct {
-	Owner   string
-	Name    string
-	TagName string
-	Date    *time.Time
+	Owner   string     `json:"owner"`
+	Name    string     `json:"repo"`
+	TagName string     `json:"tag"`
+	Date    *time.Time `json:"date"`
 }
 
 // Formating for table view output, giving relative information on when the last
@@ -49,6 +69,10 @@ type RepoReleaseTag struct {
 //  59 days ago
 //  < 12 hours
 func (r *RepoReleaseTag) LastReleaseString() string {
+	if r.Date == nil {
+		// no tags
+		return "-"
+	}
 	since := time.Since(*r.Date)
 	rawSince := since.Hours() / 24
 	daysSince := strconv.FormatFloat(rawSince, 'f', 0, 32)
@@ -60,58 +84,33 @@ func (r *RepoReleaseTag) LastReleaseString() string {
 	} else {
 		return fmt.Sprintf(layout, r.Date.Format("Mon Jan 2 15:04:05 MST 2006"), daysSince+" days ago")
 	}
-	return "-"
 }
 
 func (c ReleasesCommand) Run(args []string) int {
-	key := os.Getenv("GITHUB_API_TOKEN")
-	if key == "" {
-		c.UI.Error("Missing API Token!")
+	var opts releasesOptions
+	if err := c.process(c.flags(&opts), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
 		return 1
 	}
 
-	var sortByName bool
-	if len(args) > 0 {
-		for _, a := range args {
-			if a == "--by-name" || a == "-b" {
-				sortByName = true
-			}
-		}
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
 
-	// refactor, this is boilerplate
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-
-	client := github.NewClient(tc)
-
-	// get list of repositories across terraform-repositories, and add in
-	// hashicorp/terraform
-	nopt := &github.RepositoryListByOrgOptions{
-		Type: "public",
-	}
-	var repos []*github.Repository
-	for {
-		part, resp, err := client.Repositories.ListByOrg(ctx, "terraform-providers", nopt)
+	ctx, cancel := c.context()
+	defer cancel()
 
-		if err != nil {
-			c.UI.Warn(fmt.Sprintf("Error listing Repositories: %s", err))
-			return 1
-		}
-		repos = append(repos, part...)
-		if resp.NextPage == 0 {
-			break
-		}
-		nopt.Page = resp.NextPage
-	}
+	// get list of repositories across the configured orgs, and add in
+	// Terraform core
+	repos := c.listOrgRepos(ctx, client, c.Config.RepositoryOrgs())
 
 	var rList []*RepoReleaseTag
 	for _, n := range repos {
-		if *n.Name == "terraform-provider-scaffolding" {
-			// exclude the example repo for now
+		// exclude the example repo, see unreleased_repos
+		if c.Config.IsUnreleased(*n.FullName) {
 			continue
 		}
 		ni := RepoReleaseTag{
@@ -121,47 +120,35 @@ func (c ReleasesCommand) Run(args []string) int {
 		rList = append(rList, &ni)
 	}
 
-	// Add hashicorp/terraform
-	rList = append(rList, &RepoReleaseTag{
-		Owner: "hashicorp",
-		Name:  "terraform",
-	})
-
-	// 5 "workers" to do things concurrently
-	wCount := 5
-	wgNIssues.Add(wCount)
-
-	// queue of RepoReleaseTags to query on the release status
-	niChan := make(chan *RepoReleaseTag, len(rList))
-
-	// recieve results from Release queries
-	resultsChan := make(chan *RepoReleaseTag, len(rList))
-
-	for gr := 1; gr <= wCount; gr++ {
-		go getLatestRelease(niChan, resultsChan)
-	}
-
-	// Feed things into queue
-	for _, r := range rList {
-		niChan <- r
+	// Add core, usually hashicorp/terraform
+	if c.Config.CoreRepo != "" {
+		parts := strings.Split(c.Config.CoreRepo, "/")
+		rList = append(rList, &RepoReleaseTag{
+			Owner: parts[0],
+			Name:  parts[1],
+		})
 	}
 
-	// Close and wait on workers
-	close(niChan)
-	wgNIssues.Wait()
-	close(resultsChan)
+	// look up the latest release of each repo concurrently
+	errs := runPool(ctx, c.concurrency, len(rList), func(ctx context.Context, i int) error {
+		return getLatestRelease(ctx, client, c.Config.GitHub.PerPage, rList[i])
+	})
 
 	var tfCore *RepoReleaseTag
 	var releases []*RepoReleaseTag
-	for r := range resultsChan {
-		if r.Name == "terraform" {
+	for i, r := range rList {
+		if errs[i] != nil {
+			c.fail(r.Owner+"/"+r.Name, errs[i])
+			continue
+		}
+		if r.Owner+"/"+r.Name == c.Config.CoreRepo {
 			tfCore = r
-			break
+			continue
 		}
 		releases = append(releases, r)
 	}
 
-	if sortByName {
+	if opts.byName {
 		// sort by repo name
 		sort.Sort(ByRepoName(releases))
 	} else {
@@ -169,18 +156,56 @@ func (c ReleasesCommand) Run(args []string) int {
 		sort.Sort(ByDaysAgo(releases))
 	}
 
+	out := &releasesOutput{
+		Core:      tfCore,
+		Providers: releases,
+	}
+	if err := c.render(out); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
+	}
+
+	succeeded := len(releases)
+	if tfCore != nil {
+		succeeded++
+	}
+	return c.exitStatus(succeeded)
+}
+
+// releasesOutput is the result of `tfteam releases`
+type releasesOutput struct {
+	Core      *RepoReleaseTag   `json:"core"`
+	Providers []*RepoReleaseTag `json:"providers"`
+}
+
+func (o *releasesOutput) Header() []string {
+	return []string{"kind", "owner", "repo", "tag", "date"}
+}
+
+func (o *releasesOutput) Rows() [][]string {
+	var rows [][]string
+	if o.Core != nil {
+		rows = append(rows, []string{"core", o.Core.Owner, o.Core.Name, o.Core.TagName, formatTime(o.Core.Date)})
+	}
+	for _, r := range o.Providers {
+		rows = append(rows, []string{"provider", r.Owner, r.Name, r.TagName, formatTime(r.Date)})
+	}
+	return rows
+}
+
+func (o *releasesOutput) Table(out io.Writer) {
 	w := new(tabwriter.Writer)
-	w.Init(os.Stdout, 5, 0, 1, ' ', 0)
-	fmt.Fprintln(w, "  Core\tTag\tDate")
-	fmt.Fprintln(w, fmt.Sprintf("  %s\t%s\t%s", tfCore.Name, tfCore.TagName, tfCore.LastReleaseString()))
-	fmt.Fprintln(w)
+	w.Init(out, 5, 0, 1, ' ', 0)
+	if o.Core != nil {
+		fmt.Fprintln(w, "  Core\tTag\tDate")
+		fmt.Fprintln(w, fmt.Sprintf("  %s\t%s\t%s", o.Core.Name, o.Core.TagName, o.Core.LastReleaseString()))
+		fmt.Fprintln(w)
+	}
 	fmt.Fprintln(w, "  Provider\tTag\tDate\t")
-	for _, rTag := range releases {
+	for _, rTag := range o.Providers {
 		fmt.Fprintln(w, fmt.Sprintf("  %s\t%s\t%s", rTag.Name, rTag.TagName, rTag.LastReleaseString()))
 	}
 	w.Flush()
-
-	return 0
 }
 
 // When listing releases, list by most recently released first
@@ -189,6 +214,10 @@ type ByDaysAgo []*RepoReleaseTag
 func (a ByDaysAgo) Len() int      { return len(a) }
 func (a ByDaysAgo) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a ByDaysAgo) Less(i, j int) bool {
+	// repos without tags go last
+	if a[i].Date == nil || a[j].Date == nil {
+		return a[j].Date == nil && a[i].Date != nil
+	}
 	return a[i].Date.After(*a[j].Date)
 }
 
@@ -219,10 +248,11 @@ func (a ByTag) Less(i, j int) bool {
 	ajTag := strings.Trim(*a[j].Name, "v")
 	iparts := strings.Split(aiTag, ".")
 	jparts := strings.Split(ajTag, ".")
-	if len(iparts[1]) == 1 {
+	// tags without a minor version, ex. v1, are compared as they are
+	if len(iparts) > 1 && len(iparts[1]) == 1 {
 		iparts[1] = "0" + iparts[1]
 	}
-	if len(jparts[1]) == 1 {
+	if len(jparts) > 1 && len(jparts[1]) == 1 {
 		jparts[1] = "0" + jparts[1]
 	}
 
@@ -232,64 +262,46 @@ func (a ByTag) Less(i, j int) bool {
 	return aiTag > ajTag
 }
 
-func getLatestRelease(reposChan <-chan *RepoReleaseTag, rChan chan<- *RepoReleaseTag) {
-	defer wgNIssues.Done()
-	// should pass in and reususe context I think?
-	key := os.Getenv("GITHUB_API_TOKEN")
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-
-	client := github.NewClient(tc)
-
-	for n := range reposChan {
-		// For some reaons I don't get, this doesn't work for most of our repos...
-		// it works on Rancher, it has "Latest release" badge on it, same with
-		// hashicorp/terraform. Maybe because we're using the API to release now,
-		// and rancher some how got a manual one? dunno ౿(ఠ_ఠఎ)
-		// TODO: modify our release process to issue "create release" call in GitHub
-		// to make actual releases out of our vTags
-		// release, _, err := client.Repositories.GetLatestRelease(ctx, n.Owner, n.Name)
-
-		nopt := &github.ListOptions{}
-		var tags []*github.RepositoryTag
-		for {
-			part, resp, err := client.Repositories.ListTags(ctx, n.Owner, n.Name, nopt)
-
-			if err != nil {
-				log.Printf("Error listing tags for (%s/%s): %s", n.Owner, n.Name, err)
-				continue
-			}
-			tags = append(tags, part...)
-			if resp.NextPage == 0 {
-				break
-			}
-			nopt.Page = resp.NextPage
-		}
-
-		if len(tags) == 0 {
-			// dunno if this could happen, but saftey first
-			log.Printf("no Tags for (%s/%s)", n.Owner, n.Name)
-			n.TagName = "-"
-			continue
-		}
+// getLatestRelease sets the highest v-prefixed tag of the repo and the date
+// of its commit on n. Repos without tags get "-".
+func getLatestRelease(ctx context.Context, client *github.Client, perPage int, n *RepoReleaseTag) error {
+	// For some reaons I don't get, this doesn't work for most of our repos...
+	// it works on Rancher, it has "Latest release" badge on it, same with
+	// hashicorp/terraform. Maybe because we're using the API to release now,
+	// and rancher some how got a manual one? dunno ౿(ఠ_ఠఎ)
+	// TODO: modify our release process to issue "create release" call in GitHub
+	// to make actual releases out of our vTags
+	// release, _, err := client.Repositories.GetLatestRelease(ctx, n.Owner, n.Name)
+
+	nopt := &github.ListOptions{}
+	var tags []*github.RepositoryTag
+	err := paginate(nopt, perPage, func() (*github.Response, error) {
+		part, resp, err := client.Repositories.ListTags(ctx, n.Owner, n.Name, nopt)
+		tags = append(tags, part...)
+		return resp, err
+	})
+	if err != nil {
+		return fmt.Errorf("error listing tags: %s", err)
+	}
 
-		sort.Sort(ByTag(tags))
+	if len(tags) == 0 {
+		n.TagName = "-"
+		return nil
+	}
 
-		// in Sort I trust
-		tag := tags[0]
-		n.TagName = *tag.Name
+	sort.Sort(ByTag(tags))
 
-		// query git commit info to get the date for this commit, because we don't
-		// have true "releases"
-		commit, _, err := client.Git.GetCommit(ctx, n.Owner, n.Name, *tag.Commit.SHA)
-		if err != nil {
-			log.Printf("Error getting commit infor for (%s/%s) tag (%s): %s", n.Owner, n.Name, *tag.Commit.SHA)
-		}
-		n.Date = commit.Author.Date
+	// in Sort I trust
+	tag := tags[0]
+	n.TagName = *tag.Name
 
-		rChan <- n
+	// query git commit info to get the date for this commit, because we don't
+	// have true "releases"
+	commit, _, err := client.Git.GetCommit(ctx, n.Owner, n.Name, *tag.Commit.SHA)
+	if err != nil {
+		return fmt.Errorf("error getting commit info for tag %s: %s", *tag.Name, err)
 	}
+	n.Date = commit.Author.Date
+
+	return nil
 }
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/github"
+)
+
+// listOrgRepos returns the public repositories of each org, in order. An org
+// that can't be listed is recorded with fail and skipped.
+func (m *Meta) listOrgRepos(ctx context.Context, client *github.Client, orgs []string) []*github.Repository {
+	var repos []*github.Repository
+	for _, org := range orgs {
+		var part []*github.Repository
+		nopt := &github.RepositoryListByOrgOptions{
+			Type: "public",
+		}
+		err := paginate(&nopt.ListOptions, m.Config.GitHub.PerPage, func() (*github.Response, error) {
+			page, resp, err := client.Repositories.ListByOrg(ctx, org, nopt)
+			part = append(part, page...)
+			return resp, err
+		})
+		if err != nil {
+			m.fail(org, fmt.Errorf("error listing repositories: %s", err))
+			continue
+		}
+		repos = append(repos, part...)
+	}
+	return repos
+}
+
+// expandGroupName turns the short --type names into repo group names
+func expandGroupName(name string) string {
+	switch name {
+	case "a":
+		return "all"
+	case "h":
+		return "hashi"
+	case "c":
+		return "community"
+	}
+	return name
+}
+
+// repoGroup returns the owner/name repositories in the named group. "all"
+// lists every repository with issues in the configured orgs, and
+// "community", unless it is configured, is all of those minus "hashi".
+func (m *Meta) repoGroup(ctx context.Context, client *github.Client, name string) ([]string, error) {
+	if repos, ok := m.Config.RepoGroups[name]; ok {
+		return repos, nil
+	}
+
+	if name != "all" && name != "community" {
+		return nil, fmt.Errorf("unknown repo group %q", name)
+	}
+
+	repos := m.listOrgRepos(ctx, client, m.Config.RepositoryOrgs())
+
+	var result []string
+	for _, r := range repos {
+		if !*r.HasIssues {
+			continue
+		}
+		if name == "community" && containsString(m.Config.RepoGroups["hashi"], *r.FullName) {
+			continue
+		}
+		result = append(result, *r.FullName)
+	}
+	return result, nil
+}
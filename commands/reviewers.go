@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// SuggestReviewersCommand ranks team members as reviewers of a PR
+type SuggestReviewersCommand struct {
+	Meta
+}
+
+// suggestFiles is how many of a PR's changed files we look up the history
+// of, and suggestHistory how far back that history goes
+const (
+	suggestFiles   = 30
+	suggestHistory = 180 * 24 * time.Hour
+)
+
+func (c SuggestReviewersCommand) Help() string {
+	helpText := `
+Usage: tfteam suggest-reviewers [options] <pr-url>
+
+	Suggest team members to review a pull request. The pull request is given as
+	its URL or as owner/repo#number.
+
+	For each changed file, the authors of the commits to it in the last 6
+	months are looked up. Team members from the config are ranked by how many
+	of the changed files they touched, then by how few open pull requests are
+	waiting on their review. Only the first 30 files are looked at.
+
+%s
+
+Examples:
+
+  $ tfteam suggest-reviewers https://github.com/terraform-providers/terraform-provider-aws/pull/1629
+  Suggested reviewers for terraform-providers/terraform-provider-aws#1629 (4 files)
+
+  Reviewer      Files  Open reviews
+  radeksimko    3      2
+  catsby        1      0
+`
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flagSet("suggest-reviewers"))))
+}
+
+func (c SuggestReviewersCommand) Synopsis() string {
+	return "Suggest team members to review a PR, from the history of its files"
+}
+
+func (c SuggestReviewersCommand) Run(args []string) int {
+	positional, err := c.processArgs(c.flagSet("suggest-reviewers"), args, "pr-url")
+	if err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
+		return 1
+	}
+	owner, repo, number, err := parsePRURL(positional[0])
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	pr, _, err := client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error getting %s/%s#%d: %s", owner, repo, number, err))
+		return 1
+	}
+
+	files, err := changedFiles(ctx, client, c.Config.GitHub.PerPage, owner, repo, number)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error listing files of %s/%s#%d: %s", owner, repo, number, err))
+		return 1
+	}
+
+	touched, err := fileAuthors(ctx, client, c.Config.GitHub.PerPage, owner, repo, files)
+	if err != nil {
+		c.fail("history", err)
+	}
+	candidates := otherThan(c.Config.TeamMembers(), pr.GetUser().GetLogin())
+	load := c.openReviews(ctx, client, candidates)
+
+	out := &suggestionsOutput{
+		PR:          fmt.Sprintf("%s/%s#%d", owner, repo, number),
+		Files:       files,
+		Suggestions: rankReviewers(candidates, touched, load),
+	}
+	if err := c.render(out); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
+	}
+	return c.exitStatus(len(out.Suggestions))
+}
+
+// parsePRURL parses a PR given as its URL, ex.
+// https://github.com/hashicorp/terraform/pull/123 or one of its tabs like
+// .../pull/123/files, or as hashicorp/terraform#123
+func parsePRURL(s string) (string, string, int, error) {
+	var parts []string
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		parts = strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) < 4 || (parts[2] != "pull" && parts[2] != "pulls") {
+			parts = nil
+		} else {
+			parts = []string{parts[0], parts[1], parts[3]}
+		}
+	} else if i := strings.LastIndex(s, "#"); i > 0 && validRepoName(s[:i]) {
+		parts = append(strings.Split(s[:i], "/"), s[i+1:])
+	}
+	if parts == nil {
+		return "", "", 0, fmt.Errorf("%q is not a pull request URL or owner/repo#number", s)
+	}
+	number, err := strconv.Atoi(parts[2])
+	if err != nil || number <= 0 {
+		return "", "", 0, fmt.Errorf("%q is not a pull request URL or owner/repo#number", s)
+	}
+	return parts[0], parts[1], number, nil
+}
+
+// listPRFiles lists the files a PR changes
+func listPRFiles(ctx context.Context, client *github.Client, perPage int, owner, repo string, number int) ([]*github.CommitFile, error) {
+	var files []*github.CommitFile
+	opt := &github.ListOptions{}
+	err := paginate(opt, perPage, func() (*github.Response, error) {
+		part, resp, err := client.PullRequests.ListFiles(ctx, owner, repo, number, opt)
+		files = append(files, part...)
+		return resp, err
+	})
+	return files, err
+}
+
+// changedFiles lists the paths of the files a PR changes
+func changedFiles(ctx context.Context, client *github.Client, perPage int, owner, repo string, number int) ([]string, error) {
+	files, err := listPRFiles(ctx, client, perPage, owner, repo, number)
+	return fileNames(files), err
+}
+
+// fileNames returns the paths of files
+func fileNames(files []*github.CommitFile) []string {
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.GetFilename())
+	}
+	return names
+}
+
+// fileAuthors counts, for each login, how many of the first suggestFiles files
+// they committed to in the last suggestHistory. Only the latest page of each
+// file's history is looked at.
+func fileAuthors(ctx context.Context, client *github.Client, perPage int, owner, repo string, files []string) (map[string]int, error) {
+	if len(files) > suggestFiles {
+		files = files[:suggestFiles]
+	}
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	touched := make(map[string]int)
+	since := time.Now().Add(-suggestHistory)
+	for _, f := range files {
+		opt := &github.CommitsListOptions{Path: f, Since: since}
+		opt.PerPage = perPage
+		commits, _, err := client.Repositories.ListCommits(ctx, owner, repo, opt)
+		if err != nil {
+			return touched, fmt.Errorf("error listing commits to %s: %s", f, err)
+		}
+		seen := make(map[string]bool)
+		for _, commit := range commits {
+			login := commit.GetAuthor().GetLogin()
+			if login != "" && !seen[login] {
+				seen[login] = true
+				touched[login]++
+			}
+		}
+	}
+	return touched, nil
+}
+
+// maxSuggested is how many suggestions the Suggested column of prs shows
+const maxSuggested = 3
+
+// getSuggestedReviewers sets pr.SuggestedReviewers to the best ranked of the
+// candidates, leaving out the PR's author. It needs pr.Files, and load from
+// openReviews.
+func getSuggestedReviewers(ctx context.Context, client *github.Client, perPage int, pr *TFPr, candidates []string, load map[string]int) error {
+	touched, err := fileAuthors(ctx, client, perPage, pr.Owner, pr.Repo, pr.Files)
+	if err != nil {
+		return err
+	}
+
+	pr.SuggestedReviewers = nil
+	for _, s := range rankReviewers(otherThan(candidates, pr.GetLogin()), touched, load) {
+		if len(pr.SuggestedReviewers) == maxSuggested {
+			break
+		}
+		pr.SuggestedReviewers = append(pr.SuggestedReviewers, s.Login)
+	}
+	return nil
+}
+
+// otherThan returns logins without login, ex. the PR's author
+func otherThan(logins []string, login string) []string {
+	var others []string
+	for _, l := range logins {
+		if l != login {
+			others = append(others, l)
+		}
+	}
+	return others
+}
+
+// openReviews counts the open PRs waiting on each login's review. Logins that
+// can't be searched for are recorded with fail, and count as 0.
+func (m *Meta) openReviews(ctx context.Context, client *github.Client, logins []string) map[string]int {
+	counts := make([]int, len(logins))
+	errs := runPool(ctx, m.concurrency, len(logins), func(ctx context.Context, i int) error {
+		q := &searchQuery{}
+		q.add("is", "open")
+		q.add("is", "pr")
+		q.add("review-requested", logins[i])
+		chunks, err := q.chunks()
+		if err != nil {
+			return err
+		}
+		// only the total is needed
+		result, _, err := client.Search.Issues(ctx, chunks[0], &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 1}})
+		if err != nil {
+			return err
+		}
+		counts[i] = result.GetTotal()
+		return nil
+	})
+
+	load := make(map[string]int)
+	for i, login := range logins {
+		if errs[i] != nil {
+			m.fail(login, fmt.Errorf("error counting open reviews: %s", errs[i]))
+		}
+		load[login] = counts[i]
+	}
+	return load
+}
+
+// reviewerSuggestion is a candidate reviewer, see rankReviewers
+type reviewerSuggestion struct {
+	Login       string `json:"login"`
+	Files       int    `json:"files_touched"`
+	OpenReviews int    `json:"open_reviews"`
+}
+
+// rankReviewers orders the candidates by how many of the files they touched,
+// most first, then by their open reviews, fewest first
+func rankReviewers(candidates []string, touched, load map[string]int) []reviewerSuggestion {
+	suggestions := []reviewerSuggestion{}
+	for _, login := range candidates {
+		suggestions = append(suggestions, reviewerSuggestion{
+			Login:       login,
+			Files:       touched[login],
+			OpenReviews: load[login],
+		})
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		if a.OpenReviews != b.OpenReviews {
+			return a.OpenReviews < b.OpenReviews
+		}
+		return a.Login < b.Login
+	})
+	return suggestions
+}
+
+// suggestionsOutput is the result of `tfteam suggest-reviewers`
+type suggestionsOutput struct {
+	PR          string               `json:"pull_request"`
+	Files       []string             `json:"files"`
+	Suggestions []reviewerSuggestion `json:"suggestions"`
+}
+
+func (o *suggestionsOutput) Header() []string {
+	return []string{"rank", "login", "files_touched", "open_reviews"}
+}
+
+func (o *suggestionsOutput) Rows() [][]string {
+	var rows [][]string
+	for i, s := range o.Suggestions {
+		rows = append(rows, []string{strconv.Itoa(i + 1), s.Login, strconv.Itoa(s.Files), strconv.Itoa(s.OpenReviews)})
+	}
+	return rows
+}
+
+func (o *suggestionsOutput) Table(out io.Writer) {
+	fmt.Fprintf(out, "Suggested reviewers for %s (%d files)\n\n", o.PR, len(o.Files))
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Reviewer\tFiles\tOpen reviews")
+	for _, s := range o.Suggestions {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", s.Login, s.Files, s.OpenReviews)
+	}
+	w.Flush()
+}
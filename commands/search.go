@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// GitHub rejects searches longer than maxQueryLength, and never returns more
+// than maxSearchResults results for one search
+const (
+	maxQueryLength   = 256
+	maxSearchResults = 1000
+)
+
+// searchQualifiers are the qualifiers a searchQuery knows how to build
+var searchQualifiers = []string{
+	"author",
+	"repo",
+	"label",
+	"is",
+	"updated",
+	"closed",
+	"no",
+	"review-requested",
+	"team-review-requested",
+}
+
+// searchQuery builds an issue search that may be too long for GitHub. The
+// qualifiers given to add are in every search, while the values given to
+// anyOf are spread over as many searches as it takes, ex. one repo: per
+// repository. GitHub ORs repeated author: and repo: qualifiers, so the
+// searches together find what the whole query would have. Queries never use
+// AND, OR or NOT, which GitHub only allows five of.
+//
+//	q := &searchQuery{}
+//	q.add("is", "open")
+//	q.add("no", "label")
+//	q.anyOf("repo", repos)
+//	issues, searched := m.search(ctx, client, q)
+type searchQuery struct {
+	// Sort is the search's sort order, ex. updated, or best match if empty
+	Sort string
+
+	terms  []string
+	anyKey string
+	any    []string
+	hasAny bool
+	err    error
+}
+
+// add adds the qualifier key:value to every search
+func (q *searchQuery) add(key, value string) {
+	if err := checkQualifier(key); err != nil {
+		q.err = err
+		return
+	}
+	q.terms = append(q.terms, qualifierString(key, value))
+}
+
+// anyOf matches items with any of the values for key, ex. any of the authors.
+// An empty values matches nothing, so there's nothing to search. Only one key
+// can be given.
+func (q *searchQuery) anyOf(key string, values []string) {
+	if err := checkQualifier(key); err != nil {
+		q.err = err
+		return
+	}
+	if q.hasAny && q.anyKey != key {
+		q.err = fmt.Errorf("search can't split on both %s: and %s:", q.anyKey, key)
+		return
+	}
+	q.hasAny = true
+	q.anyKey = key
+	for _, v := range values {
+		q.any = append(q.any, qualifierString(key, v))
+	}
+}
+
+// chunks returns the searches to run, each at most maxQueryLength long
+func (q *searchQuery) chunks() ([]string, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	base := strings.Join(q.terms, " ")
+	if len(base) > maxQueryLength {
+		return nil, fmt.Errorf("search %q is longer than %d characters", base, maxQueryLength)
+	}
+	if !q.hasAny {
+		return []string{base}, nil
+	}
+
+	var chunks []string
+	chunk := base
+	added := 0
+	for _, v := range q.any {
+		next := strings.TrimSpace(chunk + " " + v)
+		if len(next) > maxQueryLength && added > 0 {
+			// start the next search
+			chunks = append(chunks, chunk)
+			chunk, added = base, 0
+			next = strings.TrimSpace(chunk + " " + v)
+		}
+		if len(next) > maxQueryLength {
+			return nil, fmt.Errorf("search for %s is longer than %d characters", v, maxQueryLength)
+		}
+		chunk = next
+		added++
+	}
+	if added > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// search runs the searches of q concurrently, and returns the issues they
+// found without duplicates, and how many of the searches succeeded. Searches
+// that fail are recorded with fail, keeping the pages they got.
+func (m *Meta) search(ctx context.Context, client *github.Client, q *searchQuery) ([]github.Issue, int) {
+	chunks, err := q.chunks()
+	if err != nil {
+		m.fail("search", err)
+		return nil, 0
+	}
+
+	results := make([][]github.Issue, len(chunks))
+	// truncated is how many results a search matched, if it matched more than
+	// maxSearchResults. The warnings are written once the pool is done, in
+	// order, rather than from the workers.
+	truncated := make([]int, len(chunks))
+	errs := runPool(ctx, m.concurrency, len(chunks), func(ctx context.Context, i int) error {
+		sopt := &github.SearchOptions{Sort: q.Sort}
+		return paginate(&sopt.ListOptions, m.Config.GitHub.PerPage, func() (*github.Response, error) {
+			sresults, resp, err := client.Search.Issues(ctx, chunks[i], sopt)
+			if err != nil {
+				return resp, err
+			}
+			results[i] = append(results[i], sresults.Issues...)
+			if len(results[i]) >= maxSearchResults && resp.NextPage != 0 {
+				// GitHub errors instead of returning the next page
+				truncated[i] = sresults.GetTotal()
+				resp.NextPage = 0
+			}
+			return resp, nil
+		})
+	})
+
+	seen := make(map[string]bool)
+	var issues []github.Issue
+	searched := 0
+	for i, err := range errs {
+		if truncated[i] > 0 {
+			m.UI.Warn(fmt.Sprintf("Search %q matched %d results, only the first %d are listed",
+				chunks[i], truncated[i], maxSearchResults))
+		}
+		if err != nil {
+			item := "search"
+			if len(chunks) > 1 {
+				item = fmt.Sprintf("search %d/%d", i+1, len(chunks))
+			}
+			m.fail(item, fmt.Errorf("error searching: %s", err))
+		} else {
+			searched++
+		}
+		for _, issue := range results[i] {
+			if seen[issue.GetHTMLURL()] {
+				continue
+			}
+			seen[issue.GetHTMLURL()] = true
+			issues = append(issues, issue)
+		}
+	}
+	return issues, searched
+}
+
+func checkQualifier(key string) error {
+	if !containsString(searchQualifiers, key) {
+		return fmt.Errorf("unknown search qualifier %s:", key)
+	}
+	return nil
+}
+
+// qualifierString is key:value, quoting values with spaces, ex.
+// label:"waiting response"
+func qualifierString(key, value string) string {
+	if strings.ContainsAny(value, " \t") {
+		value = `"` + value + `"`
+	}
+	return key + ":" + value
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchQueryChunks(t *testing.T) {
+	// twenty character logins, so each "author:login" adds 28 characters to
+	// the 7 of "is:open" and a search fits 8 of them
+	logins := make([]string, 10)
+	authors := make([]string, 10)
+	for i := range logins {
+		logins[i] = fmt.Sprintf("login%015d", i)
+		authors[i] = "author:" + logins[i]
+	}
+
+	cases := []struct {
+		Name   string
+		Terms  [][2]string
+		AnyKey string
+		Any    []string
+		Chunks []string
+		Err    string
+	}{
+		{"empty", nil, "", nil, []string{""}, ""},
+		{"empty anyOf", [][2]string{{"is", "open"}}, "author", []string{}, nil, ""},
+		{
+			"qualifiers only",
+			[][2]string{{"is", "open"}, {"label", "waiting response"}},
+			"", nil,
+			[]string{`is:open label:"waiting response"`},
+			"",
+		},
+		{
+			"anyOf without qualifiers",
+			nil, "repo", []string{"hashicorp/terraform", "hashicorp/vault"},
+			[]string{"repo:hashicorp/terraform repo:hashicorp/vault"},
+			"",
+		},
+		{
+			"split, qualifiers in every search",
+			[][2]string{{"is", "open"}}, "author", logins,
+			[]string{
+				"is:open " + strings.Join(authors[:8], " "),
+				"is:open " + strings.Join(authors[8:], " "),
+			},
+			"",
+		},
+		{
+			"exactly the limit",
+			[][2]string{{"is", "open"}}, "author", []string{strings.Repeat("a", 241)},
+			[]string{"is:open author:" + strings.Repeat("a", 241)},
+			"",
+		},
+		{
+			"value too long for an empty search",
+			[][2]string{{"is", "open"}}, "author", []string{"catsby", strings.Repeat("a", 242)},
+			nil,
+			"longer than 256 characters",
+		},
+		{
+			"qualifiers too long",
+			[][2]string{{"label", strings.Repeat("a", 251)}}, "", nil,
+			nil,
+			"longer than 256 characters",
+		},
+		{
+			"unknown qualifier",
+			[][2]string{{"state", "open"}}, "", nil,
+			nil,
+			"unknown search qualifier state:",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			q := &searchQuery{}
+			for _, term := range tc.Terms {
+				q.add(term[0], term[1])
+			}
+			if tc.AnyKey != "" {
+				q.anyOf(tc.AnyKey, tc.Any)
+			}
+
+			chunks, err := q.chunks()
+			if tc.Err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.Err) {
+					t.Fatalf("expected error containing %q, got %v", tc.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(chunks, tc.Chunks) {
+				t.Fatalf("expected %q, got %q", tc.Chunks, chunks)
+			}
+			for _, c := range chunks {
+				if len(c) > maxQueryLength {
+					t.Fatalf("search is %d characters: %q", len(c), c)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// teamGroup is a team and the logins of its members, in the order the teams
+// were given
+type teamGroup struct {
+	Name    string
+	Members []string
+}
+
+// parseTeam parses an org/slug team reference, as given to --team
+func parseTeam(s string) (*TeamConfig, error) {
+	if !validRepoName(s) {
+		return nil, fmt.Errorf("team %q is not in org/slug form", s)
+	}
+	parts := strings.Split(s, "/")
+	return &TeamConfig{Org: parts[0], Slug: parts[1]}, nil
+}
+
+// teamMembers lists the members of each team. Teams with members in the
+// config use those, the others are looked up by ID, resolving the slug to an
+// ID first if needed. Teams that can't be listed are recorded with fail.
+func (m *Meta) teamMembers(ctx context.Context, client *github.Client, teams []*TeamConfig) []teamGroup {
+	// the teams of each org, listed once for all the slugs in it
+	orgTeams := make(map[string][]*github.Team)
+
+	var groups []teamGroup
+	for _, t := range teams {
+		group := teamGroup{Name: t.String()}
+		if t.ID == 0 && len(t.Members) > 0 {
+			// no team to look up, use the members from the config
+			group.Members = append(group.Members, t.Members...)
+			groups = append(groups, group)
+			continue
+		}
+
+		id := t.ID
+		if id == 0 {
+			if _, ok := orgTeams[t.Org]; !ok {
+				var all []*github.Team
+				opt := &github.ListOptions{}
+				err := paginate(opt, m.Config.GitHub.PerPage, func() (*github.Response, error) {
+					part, resp, err := client.Organizations.ListTeams(ctx, t.Org, opt)
+					all = append(all, part...)
+					return resp, err
+				})
+				if err != nil {
+					m.fail(t.Org, fmt.Errorf("error listing teams: %s", err))
+					continue
+				}
+				orgTeams[t.Org] = all
+			}
+			for _, team := range orgTeams[t.Org] {
+				if team.GetSlug() == t.Slug {
+					id = team.GetID()
+				}
+			}
+			if id == 0 {
+				m.fail(t.String(), fmt.Errorf("no team %q in %s", t.Slug, t.Org))
+				continue
+			}
+		}
+
+		opt := &github.OrganizationListTeamMembersOptions{Role: "all"}
+		err := paginate(&opt.ListOptions, m.Config.GitHub.PerPage, func() (*github.Response, error) {
+			members, resp, err := client.Organizations.ListTeamMembers(ctx, id, opt)
+			for _, u := range members {
+				group.Members = append(group.Members, u.GetLogin())
+			}
+			return resp, err
+		})
+		if err != nil {
+			m.fail(t.String(), fmt.Errorf("error listing members: %s", err))
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/github"
@@ -13,6 +17,69 @@ type TFPr struct {
 	State   string
 	Title   string
 
+	// Reviewers maps each reviewer's login to their latest decisive review
+	// state, see setReviews
+	Reviewers map[string]string
+
+	// BlockingCommits maps the login of each reviewer who requested changes
+	// to the commit they reviewed
+	BlockingCommits map[string]string
+
+	// HeadSHA is the commit the PR's head branch points to and BaseRef the
+	// branch it merges into
+	HeadSHA string
+	BaseRef string
+
+	// Mergeable is nil while GitHub is still working it out. MergeableState
+	// is ex. clean, dirty (conflicts), behind or unknown.
+	Mergeable      *bool
+	MergeableState string
+
+	// BehindBy is how many commits BaseRef has that the head doesn't. It's
+	// only looked up with --merge-status or --conflicts, and BehindUnknown
+	// is set if the comparison failed.
+	BehindBy      int
+	BehindUnknown bool
+
+	// RequestedAt is when the review request we're listing was made, with
+	// --review-requested or --team-review-requested
+	RequestedAt *time.Time
+
+	// the PR's change footprint, see Size
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	Commits      int
+
+	// CI is the combined commit status of HeadSHA: success, pending or
+	// failure, or empty if nothing reports a status. FailingContexts names
+	// the failed or errored statuses.
+	CI              string
+	FailingContexts []string
+
+	// RequestedReviewers are the logins, and org/slug teams, whose review was
+	// requested and is still outstanding
+	RequestedReviewers []string
+
+	// Files are the paths the PR changes, and CodeOwners the users and
+	// org/team teams owning them in CODEOWNERS. They're only looked up when
+	// a flag needs them.
+	Files      []string
+	CodeOwners []string
+
+	// Checks are the resources and data sources the PR adds, with --check
+	Checks []*providerCheck
+
+	// SuggestedReviewers are the team members best placed to review the PR,
+	// with --suggest-reviewers
+	SuggestedReviewers []string
+
+	// Labels are the PR's label names, and Draft whether it was opened as a
+	// draft. WIP is set from them and the title by setWIP.
+	Labels []string
+	Draft  bool
+	WIP    bool
+
 	Owner string
 	Repo  string
 
@@ -20,13 +87,72 @@ type TFPr struct {
 	UpdatedAt *time.Time
 }
 
-// A collection of PRs that can be sorted
-type TFPRGroup []*TFPr
-
-func (t TFPRGroup) Len() int      { return len(t) }
-func (t TFPRGroup) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
-func (t TFPRGroup) Less(i, j int) bool {
-	return !t[i].CreatedAt.After(*t[j].CreatedAt)
+// MarshalJSON gives TFPr a stable schema for --format=json, instead of
+// inlining every field of the embedded github.User
+func (tfpr *TFPr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Owner     string           `json:"owner"`
+		Repo      string           `json:"repo"`
+		Number    int              `json:"number"`
+		Title     string           `json:"title"`
+		Author    string           `json:"author"`
+		URL       string           `json:"url"`
+		State     string           `json:"review_state"`
+		Status    string           `json:"status"`
+		Approvers []string         `json:"approvers"`
+		Blockers  []string         `json:"blockers"`
+		CI        string           `json:"ci"`
+		FailingCI []string         `json:"failing_ci"`
+		Mergeable *bool            `json:"mergeable"`
+		MergeWith string           `json:"mergeable_state"`
+		BehindBy  *int             `json:"behind_by"`
+		Additions int              `json:"additions"`
+		Deletions int              `json:"deletions"`
+		Files     int              `json:"changed_files"`
+		Commits   int              `json:"commits"`
+		Size      string           `json:"size"`
+		Requested *time.Time       `json:"review_requested_at"`
+		Pending   []string         `json:"requested_reviewers"`
+		Suggested []string         `json:"suggested_reviewers"`
+		Owners    []string         `json:"code_owners"`
+		Checks    []*providerCheck `json:"checks"`
+		Labels    []string         `json:"labels"`
+		Draft     bool             `json:"draft"`
+		WIP       bool             `json:"wip"`
+		CreatedAt *time.Time       `json:"created_at"`
+		UpdatedAt *time.Time       `json:"updated_at"`
+	}{
+		Owner:     tfpr.Owner,
+		Repo:      tfpr.Repo,
+		Number:    tfpr.Number,
+		Title:     tfpr.Title,
+		Author:    tfpr.GetLogin(),
+		URL:       tfpr.HTMLURL,
+		State:     tfpr.State,
+		Status:    tfpr.StatusCode().String(),
+		Approvers: tfpr.reviewersWith("APPROVED"),
+		Blockers:  tfpr.reviewersWith("CHANGES_REQUESTED"),
+		CI:        tfpr.CI,
+		FailingCI: tfpr.FailingContexts,
+		Mergeable: tfpr.Mergeable,
+		MergeWith: tfpr.MergeableState,
+		BehindBy:  tfpr.behindBy(),
+		Additions: tfpr.Additions,
+		Deletions: tfpr.Deletions,
+		Files:     tfpr.ChangedFiles,
+		Commits:   tfpr.Commits,
+		Size:      tfpr.Size(),
+		Requested: tfpr.RequestedAt,
+		Pending:   tfpr.RequestedReviewers,
+		Suggested: tfpr.SuggestedReviewers,
+		Owners:    tfpr.CodeOwners,
+		Checks:    tfpr.Checks,
+		Labels:    tfpr.Labels,
+		Draft:     tfpr.Draft,
+		WIP:       tfpr.WIP,
+		CreatedAt: tfpr.CreatedAt,
+		UpdatedAt: tfpr.UpdatedAt,
+	})
 }
 
 func (tfpr *TFPr) IsApprovedString() string {
@@ -39,6 +165,12 @@ func (tfpr *TFPr) IsApprovedString() string {
 	}
 	if "CHANGES_REQUESTED" == tfpr.State {
 		approved = "-  "
+		if tfpr.NeedsReReview() {
+			approved = "~  "
+		}
+	}
+	if tfpr.WIP {
+		approved = approved[:1] + "W" + approved[2:]
 	}
 	return approved
 }
@@ -53,10 +185,51 @@ func (tfpr *TFPr) StatusCode() PRReviewStatus {
 	}
 	if "CHANGES_REQUESTED" == tfpr.State {
 		status = StatusChanges
+		if tfpr.NeedsReReview() {
+			status = StatusUpdated
+		}
 	}
 	return status
 }
 
+// NeedsReReview is true if changes were requested, and the author pushed
+// commits after one of those reviews, so the head is no longer the reviewed
+// commit. It needs HeadSHA, see getPullRequest.
+func (tfpr *TFPr) NeedsReReview() bool {
+	if tfpr.State != "CHANGES_REQUESTED" || tfpr.HeadSHA == "" {
+		return false
+	}
+	for _, commit := range tfpr.BlockingCommits {
+		if commit != "" && commit != tfpr.HeadSHA {
+			return true
+		}
+	}
+	return false
+}
+
+// wipPrefixes are title prefixes that mark a PR as a work in progress,
+// matched ignoring case
+var wipPrefixes = []string{"[wip]", "(wip)", "wip:", "wip "}
+
+// setWIP marks the PR as a work in progress if it's a draft, its title starts
+// with one of wipPrefixes, or it has one of wipLabels
+func (tfpr *TFPr) setWIP(wipLabels []string) {
+	tfpr.WIP = tfpr.Draft
+	title := strings.ToLower(strings.TrimSpace(tfpr.Title))
+	for _, p := range wipPrefixes {
+		if strings.HasPrefix(title, p) {
+			tfpr.WIP = true
+		}
+	}
+	for _, l := range tfpr.Labels {
+		for _, wl := range wipLabels {
+			if strings.EqualFold(l, wl) {
+				tfpr.WIP = true
+			}
+		}
+	}
+}
+
 func (tfpr *TFPr) TitleTruncated() string {
 	width := 50
 	if len(tfpr.Title) < width {
@@ -70,3 +243,201 @@ func (tfpr *TFPr) TitleTruncated() string {
 	}
 	return tfpr.Title[:45] + "[...]"
 }
+
+// setReviews records each reviewer's latest decisive review in Reviewers, and
+// derives State from them: an outstanding CHANGES_REQUESTED from anyone wins,
+// then APPROVED, then COMMENTED. A comment after an approval or change request
+// doesn't replace it. DISMISSED and PENDING reviews, and the author's own
+// reviews, are ignored.
+func (tfpr *TFPr) setReviews(reviews []*github.PullRequestReview) {
+	var submitted []*github.PullRequestReview
+	for _, r := range reviews {
+		if r.SubmittedAt != nil {
+			submitted = append(submitted, r)
+		}
+	}
+	// ByReviewDate is newest first
+	sort.Sort(sort.Reverse(ByReviewDate(submitted)))
+
+	tfpr.Reviewers = make(map[string]string)
+	tfpr.BlockingCommits = make(map[string]string)
+	for _, r := range submitted {
+		login := r.GetUser().GetLogin()
+		if login == "" || login == tfpr.GetLogin() {
+			continue
+		}
+		switch r.GetState() {
+		case "APPROVED":
+			tfpr.Reviewers[login] = "APPROVED"
+			delete(tfpr.BlockingCommits, login)
+		case "CHANGES_REQUESTED":
+			tfpr.Reviewers[login] = "CHANGES_REQUESTED"
+			tfpr.BlockingCommits[login] = r.GetCommitID()
+		case "COMMENTED":
+			if _, ok := tfpr.Reviewers[login]; !ok {
+				tfpr.Reviewers[login] = "COMMENTED"
+			}
+		}
+	}
+
+	tfpr.State = ""
+	for _, state := range []string{"CHANGES_REQUESTED", "APPROVED", "COMMENTED"} {
+		if len(tfpr.reviewersWith(state)) > 0 {
+			tfpr.State = state
+			break
+		}
+	}
+}
+
+// reviewersWith returns the sorted logins of the reviewers whose latest
+// decisive review is state
+func (tfpr *TFPr) reviewersWith(state string) []string {
+	logins := []string{}
+	for login, s := range tfpr.Reviewers {
+		if s == state {
+			logins = append(logins, login)
+		}
+	}
+	sort.Strings(logins)
+	return logins
+}
+
+// ReviewersString lists approvers with a + and blockers with a -, ex.
+// "+catsby -radeksimko"
+func (tfpr *TFPr) ReviewersString() string {
+	var parts []string
+	for _, login := range tfpr.reviewersWith("APPROVED") {
+		parts = append(parts, "+"+login)
+	}
+	for _, login := range tfpr.reviewersWith("CHANGES_REQUESTED") {
+		parts = append(parts, "-"+login)
+	}
+	return strings.Join(parts, " ")
+}
+
+// IsOwnedBy is true if owner, a login or org/team, owns any of the files the
+// PR changes. It needs CodeOwners, see getCodeOwners.
+func (tfpr *TFPr) IsOwnedBy(owner string) bool {
+	owner = strings.TrimPrefix(owner, "@")
+	for _, o := range tfpr.CodeOwners {
+		if strings.EqualFold(o, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// CIString is the CI column, ex. "failure: ci/circleci, travis". PRs without
+// statuses show "-".
+func (tfpr *TFPr) CIString() string {
+	switch {
+	case tfpr.CI == "":
+		return "-"
+	case len(tfpr.FailingContexts) > 0:
+		return tfpr.CI + ": " + strings.Join(tfpr.FailingContexts, ", ")
+	}
+	return tfpr.CI
+}
+
+// MatchesCI is true if the PR's CI is in the --ci state: failing, passing or
+// pending
+func (tfpr *TFPr) MatchesCI(filter string) bool {
+	switch filter {
+	case "failing":
+		return tfpr.CI == "failure" || tfpr.CI == "error"
+	case "passing":
+		return tfpr.CI == "success"
+	case "pending":
+		return tfpr.CI == "pending"
+	}
+	return true
+}
+
+// HasConflicts is true if the PR can't be merged without a rebase
+func (tfpr *TFPr) HasConflicts() bool {
+	return (tfpr.Mergeable != nil && !*tfpr.Mergeable) || tfpr.MergeableState == "dirty"
+}
+
+// NeedsRebase is true if the PR has conflicts or is more than maxBehind
+// commits behind its base
+func (tfpr *TFPr) NeedsRebase(maxBehind int) bool {
+	return tfpr.HasConflicts() || tfpr.BehindBy > maxBehind
+}
+
+// behindBy is BehindBy for the json output, or nil if it's unknown
+func (tfpr *TFPr) behindBy() *int {
+	if tfpr.BehindUnknown {
+		return nil
+	}
+	return &tfpr.BehindBy
+}
+
+// MergeString is the Merge column, ex. "conflicts" or "behind 12". PRs more
+// than maxBehind commits behind are marked with a "!".
+func (tfpr *TFPr) MergeString(maxBehind int) string {
+	switch {
+	case tfpr.HasConflicts():
+		return "conflicts"
+	case tfpr.BehindUnknown:
+		return "unknown"
+	case tfpr.BehindBy > maxBehind:
+		return fmt.Sprintf("behind %d!", tfpr.BehindBy)
+	case tfpr.BehindBy > 0:
+		return fmt.Sprintf("behind %d", tfpr.BehindBy)
+	case tfpr.Mergeable == nil:
+		return "unknown"
+	}
+	return "ok"
+}
+
+// prSizes are the size buckets, smallest first, with the number of changed
+// lines each one goes up to
+var prSizes = []struct {
+	Name  string
+	Below int
+}{
+	{"XS", 10},
+	{"S", 30},
+	{"M", 100},
+	{"L", 500},
+	{"XL", -1},
+}
+
+// sizeIndex returns the position of the named bucket in prSizes, or -1
+func sizeIndex(name string) int {
+	for i, s := range prSizes {
+		if strings.EqualFold(s.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Size buckets the PR by its changed lines, additions plus deletions: XS
+// under 10, S under 30, M under 100, L under 500 and XL for the rest
+func (tfpr *TFPr) Size() string {
+	return prSizes[tfpr.sizeIndex()].Name
+}
+
+func (tfpr *TFPr) sizeIndex() int {
+	lines := tfpr.Additions + tfpr.Deletions
+	for i, s := range prSizes {
+		if s.Below < 0 || lines < s.Below {
+			return i
+		}
+	}
+	return len(prSizes) - 1
+}
+
+// SizeString is the Size column, ex. "M +80 -12, 3 files"
+func (tfpr *TFPr) SizeString() string {
+	return fmt.Sprintf("%s +%d -%d, %d files", tfpr.Size(), tfpr.Additions, tfpr.Deletions, tfpr.ChangedFiles)
+}
+
+// WaitingString is how long the review request has been waiting, ex. "3d 4h"
+func (tfpr *TFPr) WaitingString() string {
+	if tfpr.RequestedAt == nil {
+		return "-"
+	}
+	return formatAge(time.Since(*tfpr.RequestedAt))
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestTFPrSetReviews(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	// review is by login, minute minutes after start. A negative minute
+	// leaves it unsubmitted, like a PENDING review.
+	review := func(login, state string, minute int) *github.PullRequestReview {
+		r := &github.PullRequestReview{
+			User:     &github.User{Login: github.String(login)},
+			State:    github.String(state),
+			CommitID: github.String("sha"),
+		}
+		if minute >= 0 {
+			at := start.Add(time.Duration(minute) * time.Minute)
+			r.SubmittedAt = &at
+		}
+		return r
+	}
+
+	cases := []struct {
+		Name      string
+		Reviews   []*github.PullRequestReview
+		Reviewers map[string]string
+		State     string
+	}{
+		{"no reviews", nil, map[string]string{}, ""},
+		{
+			"latest decisive review wins",
+			[]*github.PullRequestReview{
+				review("catsby", "APPROVED", 2),
+				review("catsby", "CHANGES_REQUESTED", 1),
+				review("radeksimko", "APPROVED", 1),
+				review("radeksimko", "CHANGES_REQUESTED", 2),
+			},
+			map[string]string{"catsby": "APPROVED", "radeksimko": "CHANGES_REQUESTED"},
+			"CHANGES_REQUESTED",
+		},
+		{
+			"changes requested then approved",
+			[]*github.PullRequestReview{
+				review("catsby", "CHANGES_REQUESTED", 1),
+				review("catsby", "APPROVED", 2),
+			},
+			map[string]string{"catsby": "APPROVED"},
+			"APPROVED",
+		},
+		{
+			"dismissed and pending ignored",
+			[]*github.PullRequestReview{
+				review("catsby", "DISMISSED", 1),
+				review("radeksimko", "APPROVED", 1),
+				review("radeksimko", "PENDING", -1),
+				review("paddycarver", "PENDING", -1),
+			},
+			map[string]string{"radeksimko": "APPROVED"},
+			"APPROVED",
+		},
+		{
+			"author's own reviews ignored",
+			[]*github.PullRequestReview{
+				review("author", "CHANGES_REQUESTED", 1),
+				review("author", "APPROVED", 2),
+				review("catsby", "COMMENTED", 3),
+			},
+			map[string]string{"catsby": "COMMENTED"},
+			"COMMENTED",
+		},
+		{
+			"comment doesn't override approval",
+			[]*github.PullRequestReview{
+				review("catsby", "APPROVED", 1),
+				review("catsby", "COMMENTED", 2),
+			},
+			map[string]string{"catsby": "APPROVED"},
+			"APPROVED",
+		},
+		{
+			"comment doesn't override change request",
+			[]*github.PullRequestReview{
+				review("catsby", "COMMENTED", 3),
+				review("catsby", "CHANGES_REQUESTED", 2),
+			},
+			map[string]string{"catsby": "CHANGES_REQUESTED"},
+			"CHANGES_REQUESTED",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			pr := &TFPr{User: &github.User{Login: github.String("author")}}
+			pr.setReviews(tc.Reviews)
+			if !reflect.DeepEqual(pr.Reviewers, tc.Reviewers) {
+				t.Fatalf("expected reviewers %v, got %v", tc.Reviewers, pr.Reviewers)
+			}
+			if pr.State != tc.State {
+				t.Fatalf("expected state %q, got %q", tc.State, pr.State)
+			}
+		})
+	}
+}
@@ -1,27 +1,24 @@
 package commands
 
 import (
-	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
-	"html/template"
-	"log"
-	"os"
+	"io"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
-
-	"golang.org/x/oauth2"
+	"text/template"
 
 	"github.com/google/go-github/github"
-	"github.com/mitchellh/cli"
 )
 
 // TriageCommand represents the go-cli command
 type TriageCommand struct {
-	UI cli.Ui
+	Meta
 }
 
-var resultsMap = make(map[string][]github.Issue)
-
 // Help outputs text usage help
 func (c TriageCommand) Help() string {
 	helpText := `
@@ -29,20 +26,14 @@ Usage: tfteam triage [options]
 	
 	List unlabeld issues from terraform-providers org from the past 24 hours. 
 
-Options:
-
-	--all, -a          List all issues and Pull Requests
+	The --type option picks which provider repositories to search:
 
-	--pulls, -p        Only list Pull Requests 
+	  - "[a]ll" - every provider in the configured orgs
+	  - "[h]ashi" - hashicorp ones: vault, nomad, aws, gcp, azure, consul
+	  - "[c]ommunity" - (all - hashi), unless configured
+	  - any other repo group from the config file
 
-	--repository, -r        Only list items from these repositories. Comma seperated
-
-	--type, -t         Provider type to search: 
-                             - "[a]ll" - every provider in terraform-providers
-                             - "[h]ashi" - hashicorp ones: vault, nomad, aws, gcp, azure, consul 
-                             - "[c]ommunity" - (all - hashi) # doesn't work yet
-                         
-                           Default: hashi
+%s
 
 Examples:
 
@@ -54,7 +45,25 @@ Examples:
 
 
 `
-	return strings.TrimSpace(helpText)
+	return strings.TrimSpace(fmt.Sprintf(helpText, flagsHelp(c.flags(&triageOptions{}))))
+}
+
+// triageOptions are the flags accepted by `tfteam triage`
+type triageOptions struct {
+	all          bool
+	pulls        bool
+	repositories []string
+	repoType     string
+}
+
+func (c *TriageCommand) flags(o *triageOptions) *flag.FlagSet {
+	f := c.flagSet("triage")
+	boolVar(f, &o.all, "all", "a", "List all issues and Pull Requests, from every provider")
+	boolVar(f, &o.pulls, "pulls", "p", "Only list Pull Requests")
+	listVar(f, &o.repositories, "repository", "r", "Only list items from these `repositories`. Comma seperated")
+	listVar(f, &o.repositories, "repositories", "", "")
+	stringVar(f, &o.repoType, "type", "t", "Provider `type` to search, see above. Default: hashi")
+	return f
 }
 
 // Synopsis should do something, but it doesn't
@@ -62,112 +71,48 @@ func (c TriageCommand) Synopsis() string {
 	return "List issues from Terraform* repositories with no label"
 }
 
-// searchResult wraps the result of a search done concurrently
-type searchResult struct {
-	Name   string
-	Issues []github.Issue
-}
-
 // Run executes the command
 func (c TriageCommand) Run(args []string) int {
-	key := os.Getenv("GITHUB_API_TOKEN")
-	if key == "" {
-		c.UI.Error("Missing API Token!")
+	var opts triageOptions
+	if err := c.process(c.flags(&opts), args); err != nil {
+		c.UI.Error(err.Error())
+		c.UI.Error(c.Help())
 		return 1
 	}
 
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: key},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	// by default, only show issues
-	repoTypeFilter := []string{
-		"terraform-providers/terraform-provider-aws",
-		"terraform-providers/terraform-provider-azurerm",
-		"terraform-providers/terraform-provider-consul",
-		"terraform-providers/terraform-provider-google",
-		"terraform-providers/terraform-provider-kubernetes",
-		"terraform-providers/terraform-provider-nomad",
-		"terraform-providers/terraform-provider-opc",
-		"terraform-providers/terraform-provider-vault",
-		"terraform-providers/terraform-provider-vsphere",
+	client, err := c.client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
 
-	// filter out to only use these specific repos
-	// where -t / --type filters by type (all, hashicorp, community), -r filters
-	// further to specific named repos
-	repoNameFilter := []string{}
-
-	filter := "is:issue"
-
-	if len(args) > 0 {
-		for _, a := range args {
-			if a == "--pulls" || a == "-p" {
-				filter = "is:pr"
-			}
-
-			if a == "--all" || a == "-a" {
-				filter = ""
-			}
-
-			if strings.Contains(a, "--repositories") || strings.Contains(a, "-r") {
-				parts := strings.Split(a, "=")
-				// parts 0 is "--users" or "-u"
-				if len(parts) > 1 {
-					repoNameFilter = strings.Split(parts[1], ",")
-				} else {
-					log.Printf("no repo filter given")
-				}
-			}
-
-			// default with just hashi repos. If we wwant all, clear the filter list
-			if a == "--all" || a == "-a" {
-				repoTypeFilter = []string{}
-			}
+	ctx, cancel := c.context()
+	defer cancel()
 
-			if strings.Contains(a, "--type") || strings.Contains(a, "-t") {
-				parts := strings.Split(a, "=")
-				// parts 0 is "--users" or "-u"
-				if len(parts) > 1 && parts[1] == "a" || parts[1] == "all" {
-					repoTypeFilter = []string{}
-				} else {
-					log.Printf("no filter user given")
-				}
-			}
-		}
+	// by default, only show issues
+	filter := "issue"
+	if opts.pulls {
+		filter = "pr"
+	}
+	if opts.all {
+		filter = ""
 	}
 
-	// only get org repos if we aren't filtering
-	if len(repoTypeFilter) == 0 {
-		// get list of repositories across terraform-repositories
-		// TODO: this was copy-pasta'd from commands/releases.go
-		nopt := &github.RepositoryListByOrgOptions{
-			Type: "public",
-		}
-		var repos []*github.Repository
-		for {
-			part, resp, err := client.Repositories.ListByOrg(ctx, "terraform-providers", nopt)
-
-			if err != nil {
-				c.UI.Warn(fmt.Sprintf("Error listing Repositories: %s", err))
-				return 1
-			}
-			repos = append(repos, part...)
-			if resp.NextPage == 0 {
-				break
-			}
-			nopt.Page = resp.NextPage
-		}
+	// -t / --type filters by type (all, hashicorp, community), -r filters
+	// further to specific named repos. Default with just hashi repos.
+	groupName := "hashi"
+	if opts.all {
+		groupName = "all"
+	}
+	if opts.repoType != "" {
+		groupName = expandGroupName(opts.repoType)
+	}
+	repoNameFilter := opts.repositories
 
-		for _, r := range repos {
-			if !*r.HasIssues {
-				continue
-			}
-			repoTypeFilter = append(repoTypeFilter, "terraform-providers/"+*r.Name)
-		}
+	repoTypeFilter, err := c.repoGroup(ctx, client, groupName)
+	if err != nil {
+		c.UI.Warn(fmt.Sprintf("Error listing Repositories: %s", err))
+		return 1
 	}
 
 	// apply repo filter
@@ -186,101 +131,64 @@ func (c TriageCommand) Run(args []string) int {
 		}
 	}
 
-	// If the number of repos is larger than 10 (arbitrary), cut the string in
-	// half and search 2x b/c github search was barfing on one giant string
-
-	// collect search string parts
-	var parts []string
-	if len(repoTypeFilter) > 10 {
-		// this logic is ugly and needs to be refactored, but it's a hack job that
-		// does it for now
-		half := len(repoTypeFilter) / 2
-		p1 := repoTypeFilter[:half]
-		p2 := repoTypeFilter[half:]
-
-		// repoStr := "repo:"
-		repoStr := "repo:"
-
-		repoStr1 := repoStr + strings.Join(p1, " repo:")
-		repoStr2 := repoStr + strings.Join(p2, " repo:")
-		parts = []string{repoStr1, repoStr2}
-	} else {
-		repoStr := "repo:"
-		repoStr1 := repoStr + strings.Join(repoTypeFilter, " repo:")
-		parts = []string{repoStr1}
+	q := &searchQuery{Sort: "updated"}
+	q.add("is", "open")
+	q.add("no", "label")
+	if filter != "" {
+		q.add("is", filter)
 	}
+	q.anyOf("repo", repoTypeFilter)
+	issues, searched := c.search(ctx, client, q)
 
-	var issues []github.Issue
-
-	for _, s := range parts {
-		sopt := &github.SearchOptions{Sort: "updated"}
-
-		for {
-			sresults, resp, err := client.Search.Issues(ctx, fmt.Sprintf("state:open no:label %s %s", s, filter), sopt)
-			if err != nil {
-				log.Printf("Error Searching Issues: %s", err)
-				break
-			}
-			issues = append(issues, sresults.Issues...)
-			if resp.NextPage == 0 {
-				break
-			}
-			sopt.Page = resp.NextPage
-		}
+	r := newReport(issues)
+	if err := c.render(r); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering output: %s", err))
+		return 1
 	}
 
-	fmt.Printf("Results count: %d\n\n", len(issues))
+	return c.exitStatus(searched)
+}
 
+// report is the result of `tfteam triage` and `tfteam waiting`, issues grouped
+// by repository
+type report struct {
+	RepoCount       int
+	TotalIssueCount int
+	SortedKeys      []string
+	Results         map[string][]github.Issue
+}
+
+func newReport(issues []github.Issue) *report {
+	results := make(map[string][]github.Issue)
 	for _, i := range issues {
-		key := strings.TrimPrefix(*i.RepositoryURL, "https://api.github.com/repos/terraform-providers/")
-		resultsMap[key] = append(resultsMap[key], i)
+		key := path.Base(*i.RepositoryURL)
+		results[key] = append(results[key], i)
 	}
 
 	var keys []string
-	for k := range resultsMap {
+	for k := range results {
 		keys = append(keys, k)
 	}
 
 	// alpha sort
 	sort.Strings(keys)
 
-	r := report{
-		RepoCount:       len(resultsMap),
+	return &report{
+		RepoCount:       len(results),
 		TotalIssueCount: len(issues),
 		SortedKeys:      keys,
-		Results:         resultsMap,
-	}
-
-	rp, err := template.New("report").
-		Funcs(template.FuncMap{
-			"issueList":      issueList,
-			"repoIssueCount": repoIssueCount,
-		}).
-		Parse(templ)
-	if err != nil {
-		log.Fatalf("error parsing template: %s", err)
-	}
-
-	if err := rp.Execute(os.Stdout, r); err != nil {
-		log.Fatalf("error executing template result: %s", err)
+		Results:         results,
 	}
-
-	return 0
 }
 
-type report struct {
-	RepoCount       int
-	TotalIssueCount int
-	SortedKeys      []string
-	Results         map[string][]github.Issue
-}
+const templ = `Results count: {{.TotalIssueCount}}
+
 
-const templ = `
 Results: 
 {{range .SortedKeys}}
 
-{{.}} ({{. | repoIssueCount}})
-{{. | issueList}}
+{{.}} ({{$.RepoIssueCount .}})
+{{$.IssueList .}}
 {{end}}
 
 ----------
@@ -290,21 +198,80 @@ Total unlabeled issue count: {{.TotalIssueCount}}
 
 `
 
-func issueList(key string) string {
-	l := resultsMap[key]
+func (r *report) IssueList(key string) string {
+	l := r.Results[key]
 
 	var result string
 	for _, i := range l {
-		itemType := "[i]"
-		if i.PullRequestLinks != nil {
-			itemType = "[p]"
-		}
-		str := fmt.Sprintf("#%6d %s %-75s %s", *i.Number, itemType, *i.HTMLURL, *i.Title)
+		str := fmt.Sprintf("#%6d %s %-75s %s", *i.Number, issueType(i), *i.HTMLURL, *i.Title)
 		result = result + "\n" + str
 	}
 	return result
 }
 
-func repoIssueCount(key string) int {
-	return len(resultsMap[key])
+func (r *report) RepoIssueCount(key string) int {
+	return len(r.Results[key])
+}
+
+func issueType(i github.Issue) string {
+	if i.PullRequestLinks != nil {
+		return "[p]"
+	}
+	return "[i]"
+}
+
+func (r *report) Header() []string {
+	return []string{"repo", "number", "type", "title", "url"}
+}
+
+func (r *report) Rows() [][]string {
+	var rows [][]string
+	for _, k := range r.SortedKeys {
+		for _, i := range r.Results[k] {
+			rows = append(rows, []string{k, strconv.Itoa(*i.Number), strings.Trim(issueType(i), "[]"), *i.Title, *i.HTMLURL})
+		}
+	}
+	return rows
+}
+
+func (r *report) Table(w io.Writer) {
+	rp := template.Must(template.New("report").Parse(templ))
+	if err := rp.Execute(w, r); err != nil {
+		fmt.Fprintf(w, "error executing template result: %s\n", err)
+	}
+}
+
+// MarshalJSON gives the report a stable schema for --format=json
+func (r *report) MarshalJSON() ([]byte, error) {
+	type item struct {
+		Number int    `json:"number"`
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		URL    string `json:"url"`
+	}
+	type repo struct {
+		Name  string `json:"name"`
+		Items []item `json:"items"`
+	}
+	out := struct {
+		RepoCount  int    `json:"repo_count"`
+		TotalCount int    `json:"total_count"`
+		Repos      []repo `json:"repositories"`
+	}{
+		RepoCount:  r.RepoCount,
+		TotalCount: r.TotalIssueCount,
+		Repos:      []repo{},
+	}
+	for _, k := range r.SortedKeys {
+		rp := repo{Name: k}
+		for _, i := range r.Results[k] {
+			t := "issue"
+			if i.PullRequestLinks != nil {
+				t = "pr"
+			}
+			rp.Items = append(rp.Items, item{Number: *i.Number, Type: t, Title: *i.Title, URL: *i.HTMLURL})
+		}
+		out.Repos = append(out.Repos, rp)
+	}
+	return json.Marshal(out)
 }
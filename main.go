@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/catsby/tfteam/commands"
 	"github.com/mitchellh/cli"
@@ -25,30 +28,98 @@ func main() {
 		},
 	}
 
+	// cancel the commands on the first Ctrl-C so they can print what they
+	// have so far, and give up on the second
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		ui.Warn("Interrupted, stopping. Press Ctrl-C again to exit immediately.")
+		cancel()
+		<-sigCh
+		os.Exit(130)
+	}()
+
+	meta := commands.Meta{
+		UI:      ui,
+		Context: ctx,
+	}
+
 	c.Commands = map[string]cli.CommandFactory{
 		"prs": func() (cli.Command, error) {
 			return &commands.PRsCommand{
-				UI: ui,
+				Meta: meta,
+			}, nil
+		},
+		"assign-reviews": func() (cli.Command, error) {
+			return &commands.AssignReviewsCommand{
+				Meta: meta,
 			}, nil
 		},
 		"notifications": func() (cli.Command, error) {
 			return &commands.NotificationsCommand{
-				UI: ui,
+				Meta: meta,
+			}, nil
+		},
+		"suggest-reviewers": func() (cli.Command, error) {
+			return &commands.SuggestReviewersCommand{
+				Meta: meta,
+			}, nil
+		},
+		"pr-check": func() (cli.Command, error) {
+			return &commands.PRCheckCommand{
+				Meta: meta,
 			}, nil
 		},
 		"releases": func() (cli.Command, error) {
 			return &commands.ReleasesCommand{
-				UI: ui,
+				Meta: meta,
 			}, nil
 		},
 		"triage": func() (cli.Command, error) {
 			return &commands.TriageCommand{
-				UI: ui,
+				Meta: meta,
 			}, nil
 		},
 		"waiting": func() (cli.Command, error) {
 			return &commands.WaitingCommand{
-				UI: ui,
+				Meta: meta,
+			}, nil
+		},
+		"cache": func() (cli.Command, error) {
+			return &commands.CacheCommand{
+				Meta: meta,
+			}, nil
+		},
+		"cache clear": func() (cli.Command, error) {
+			return &commands.CacheClearCommand{
+				Meta: meta,
+			}, nil
+		},
+		"cache stats": func() (cli.Command, error) {
+			return &commands.CacheStatsCommand{
+				Meta: meta,
+			}, nil
+		},
+		"metrics": func() (cli.Command, error) {
+			return &commands.MetricsCommand{
+				Meta: meta,
+			}, nil
+		},
+		"metrics reviews": func() (cli.Command, error) {
+			return &commands.MetricsReviewsCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config": func() (cli.Command, error) {
+			return &commands.ConfigCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config validate": func() (cli.Command, error) {
+			return &commands.ConfigValidateCommand{
+				Meta: meta,
 			}, nil
 		},
 	}